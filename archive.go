@@ -0,0 +1,76 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+)
+
+// archiveSeparator separates the location of an archive from the path of
+// an entry within it, like in jar: URLs.
+const archiveSeparator = "!/"
+
+var archiveExtensions = map[string]bool{
+	".zip": true,
+	".jar": true,
+	".war": true,
+	".ear": true,
+}
+
+// splitArchive splits the location of an entry of a zip archive, such as
+// service.jar!/META-INF/wsdl/Service.wsdl, into the location of the archive
+// and the path of the entry.
+func splitArchive(location string) (archive, entry string, ok bool) {
+	i := strings.Index(location, archiveSeparator)
+	if i < 0 || !archiveExtensions[strings.ToLower(path.Ext(location[:i]))] {
+		return "", "", false
+	}
+	return location[:i], location[i+len(archiveSeparator):], true
+}
+
+// archiveLoader loads the entries of a zip archive. The archive is read,
+// from disk or the network, the first time an entry is loaded.
+type archiveLoader struct {
+	g   *GoWSDL
+	loc *Location
+
+	once sync.Once
+	fsys fs.FS
+	err  error
+}
+
+func (a *archiveLoader) Load(location string) ([]byte, error) {
+	a.once.Do(func() {
+		var data []byte
+		if data, a.err = a.g.fetchFile(a.loc); a.err != nil {
+			return
+		}
+		a.fsys, a.err = zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	})
+	if a.err != nil {
+		return nil, a.err
+	}
+	return fs.ReadFile(a.fsys, location)
+}
+
+// withArchive reads the WSDL and the documents it refers to with relative
+// locations from the zip archive at archive.
+func (g *GoWSDL) withArchive(archive string) error {
+	if _, ok := g.loaders[""]; ok {
+		return errors.New("WSDL in an archive cannot be read with a loader of paths")
+	}
+	loc, err := g.parseLocation(archive)
+	if err != nil {
+		return err
+	}
+	WithLoader("", &archiveLoader{g: g, loc: loc})(g)
+	return nil
+}
@@ -0,0 +1,108 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const archiveServiceWSDL = `<?xml version="1.0" encoding="utf-8"?>
+<wsdl:definitions xmlns:wsdl="http://schemas.xmlsoap.org/wsdl/"
+                  xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"
+                  xmlns:tns="urn:root"
+                  targetNamespace="urn:root">
+  <wsdl:import namespace="urn:root" location="port/ServicePort.wsdl"/>
+  <wsdl:service name="Service">
+    <wsdl:port name="ServicePort" binding="tns:ServiceBinding">
+      <soap:address location="http://localhost/service"/>
+    </wsdl:port>
+  </wsdl:service>
+</wsdl:definitions>`
+
+const archivePortWSDL = `<?xml version="1.0" encoding="utf-8"?>
+<wsdl:definitions xmlns:xs="http://www.w3.org/2001/XMLSchema"
+                  xmlns:wsdl="http://schemas.xmlsoap.org/wsdl/"
+                  xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"
+                  xmlns:tns="urn:root"
+                  xmlns:types="urn:types"
+                  targetNamespace="urn:root">
+  <wsdl:types>
+    <xs:schema targetNamespace="urn:root">
+      <xs:import namespace="urn:types" schemaLocation="../../xsd/types.xsd"/>
+    </xs:schema>
+  </wsdl:types>
+  <wsdl:message name="ImportedMessage">
+    <wsdl:part name="parameters" element="types:Imported"/>
+  </wsdl:message>
+  <wsdl:portType name="ServicePortType">
+    <wsdl:operation name="Echo">
+      <wsdl:input message="tns:ImportedMessage"/>
+      <wsdl:output message="tns:ImportedMessage"/>
+    </wsdl:operation>
+  </wsdl:portType>
+  <wsdl:binding name="ServiceBinding" type="tns:ServicePortType">
+    <soap:binding style="document" transport="http://schemas.xmlsoap.org/soap/http"/>
+    <wsdl:operation name="Echo">
+      <soap:operation soapAction="urn:echo"/>
+      <wsdl:input><soap:body use="literal"/></wsdl:input>
+      <wsdl:output><soap:body use="literal"/></wsdl:output>
+    </wsdl:operation>
+  </wsdl:binding>
+</wsdl:definitions>`
+
+func TestArchive(t *testing.T) {
+	jar := filepath.Join(t.TempDir(), "service.jar")
+	f, err := os.Create(jar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, content := range map[string]string{
+		"META-INF/wsdl/Service.wsdl":          archiveServiceWSDL,
+		"META-INF/wsdl/port/ServicePort.wsdl": archivePortWSDL,
+		"META-INF/xsd/types.xsd":              downloadXSD,
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := NewGoWSDL(jar+"!/META-INF/wsdl/Service.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(resp["types"]), "type Imported string") {
+		t.Errorf("schema of the imported WSDL not generated:\n%s", resp["types"])
+	}
+	if !strings.Contains(string(resp["operations"]), "func (service *servicePortType) Echo (") {
+		t.Errorf("operation of the imported WSDL not generated:\n%s", resp["operations"])
+	}
+
+	g, err = NewGoWSDL(jar+"!/META-INF/wsdl/Missing.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Start(); err == nil {
+		t.Error("expected a missing archive entry to fail")
+	}
+}
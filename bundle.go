@@ -0,0 +1,613 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Bundle is a WSDL along with the WSDLs and schemas it transitively refers
+// to, with the locations of the references between them rewritten to the
+// names of their files, so that they can be stored side by side.
+type Bundle struct {
+	// Main is the file name of the WSDL.
+	Main string
+	// Files holds the content of the documents by file name.
+	Files map[string][]byte
+}
+
+// Write writes the files of the bundle into dir, creating it if needed.
+func (b *Bundle) Write(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for name, data := range b.Files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Bundle loads the WSDL and every WSDL and schema it transitively refers to
+// through the location of a wsdl:import, or the schemaLocation of an
+// xs:import, xs:include, xs:redefine or xs:override. Imports without a
+// location are left as they are, unless the catalogs map their namespace,
+// in which case they get the location of the bundled schema.
+//
+// Files are named after the last element of their location, so names are
+// stable as long as the documents are.
+func (g *GoWSDL) Bundle() (*Bundle, error) {
+	docs, err := g.bundleDocs()
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bundle{Main: docs[0].name, Files: make(map[string][]byte)}
+	for _, doc := range docs {
+		var edits []edit
+		for _, ref := range doc.refs {
+			text := strconv.Quote(ref.doc.name)
+			if ref.added {
+				text = " schemaLocation=" + text
+			}
+			edits = append(edits, edit{ref.value[0], ref.value[1], text})
+		}
+		b.Files[doc.name] = splice(doc.data, 0, len(doc.data), edits)
+	}
+	return b, nil
+}
+
+// Flatten returns a single WSDL holding the definitions of the WSDL and of
+// the WSDLs it transitively imports, with every schema they refer to
+// inlined in its types. Imports lose their schemaLocation, and includes
+// are replaced by the included schema. Redefines and overrides cannot be
+// flattened.
+func (g *GoWSDL) Flatten() ([]byte, error) {
+	docs, err := g.bundleDocs()
+	if err != nil {
+		return nil, err
+	}
+	main := docs[0]
+	if !main.wsdl {
+		return nil, fmt.Errorf("%s is not a WSDL", main.loc)
+	}
+	for _, doc := range docs {
+		for _, ref := range doc.refs {
+			if ref.kind == "redefine" || ref.kind == "override" {
+				return nil, fmt.Errorf("%s cannot be flattened: it is the target of an xs:%s in %s", ref.doc.loc, ref.kind, doc.loc)
+			}
+		}
+	}
+
+	f := &flattener{seen: make(map[string]bool)}
+	f.addWSDL(main)
+
+	root := main.elems[0]
+	rootName := qualifiedName(main.data, root)
+	prefix := ""
+	if i := strings.Index(rootName, ":"); i >= 0 {
+		prefix = rootName[:i+1]
+	}
+	scope := root.scope()
+
+	var types bytes.Buffer
+	fmt.Fprintf(&types, "<%stypes>\n", prefix)
+	for _, s := range f.schemas {
+		types.Write(s.render(scope))
+		types.WriteString("\n")
+	}
+	fmt.Fprintf(&types, "</%stypes>", prefix)
+
+	var edits []edit
+	for _, ref := range main.refs {
+		if ref.kind == "wsdl" {
+			edits = append(edits, edit{ref.el.start, ref.el.end, ""})
+		}
+	}
+	typesAt := root.tagEnd
+	replaced := false
+	for _, el := range main.elems {
+		if el.depth != 1 || el.name.Space != wsdlNamespace {
+			continue
+		}
+		switch el.name.Local {
+		case "import", "documentation":
+			typesAt = el.end
+		case "types":
+			edits = append(edits, edit{el.start, el.end, types.String()})
+			replaced = true
+		}
+	}
+	if !replaced {
+		edits = append(edits, edit{typesAt, typesAt, "\n" + types.String()})
+	}
+
+	var definitions bytes.Buffer
+	for _, doc := range f.wsdls[1:] {
+		for _, el := range doc.elems {
+			if el.depth != 1 || (el.name.Space == wsdlNamespace && (el.name.Local == "import" || el.name.Local == "types" || el.name.Local == "documentation")) {
+				continue
+			}
+			definitions.WriteString("\n")
+			definitions.Write(splice(doc.data, el.start, el.end, []edit{declareNamespaces(doc.data, el, scope, "")}))
+		}
+	}
+	if definitions.Len() > 0 {
+		end := bytes.LastIndex(main.data[:root.end], []byte("</"))
+		edits = append(edits, edit{end, end, definitions.String() + "\n"})
+	}
+
+	return splice(main.data, 0, len(main.data), edits), nil
+}
+
+// bundleDoc is a WSDL or schema document of a bundle.
+type bundleDoc struct {
+	loc  *Location
+	name string
+	wsdl bool
+	data []byte
+	// elems are the root element and its children and grandchildren, along
+	// with the elements referring to other documents, in document order.
+	elems []*xmlElement
+	refs  []*bundleRef
+}
+
+// bundleRef is a reference from a document of a bundle to another one.
+type bundleRef struct {
+	el *xmlElement
+	// kind is import, include, redefine or override, or wsdl for the
+	// imports of WSDLs.
+	kind string
+	// attr and value are the spans of the location attribute, and of its
+	// quoted value, in the referencing document.
+	attr  [2]int
+	value [2]int
+	// added is set for imports resolved through the catalogs by
+	// namespace, whose location attribute is to be added where value is.
+	added bool
+	doc   *bundleDoc
+}
+
+// bundleDocs loads the WSDL and the documents it transitively refers to,
+// the WSDL first.
+func (g *GoWSDL) bundleDocs() ([]*bundleDoc, error) {
+	if len(g.catalogs) > 0 && g.catalog == nil {
+		var err error
+		if g.catalog, err = g.loadCatalogs(g.catalogs); err != nil {
+			return nil, err
+		}
+	}
+
+	b := &bundler{g: g, byLoc: make(map[string]*bundleDoc), names: make(map[string]bool)}
+	if _, err := b.add(g.loc); err != nil {
+		return nil, err
+	}
+	return b.docs, nil
+}
+
+type bundler struct {
+	g     *GoWSDL
+	docs  []*bundleDoc
+	byLoc map[string]*bundleDoc
+	names map[string]bool
+}
+
+func (b *bundler) add(loc *Location) (*bundleDoc, error) {
+	if doc, ok := b.byLoc[loc.canonical()]; ok {
+		return doc, nil
+	}
+
+	data, err := b.g.fetchFile(loc)
+	if err != nil {
+		return nil, err
+	}
+	elems, err := scanElements(data, func(depth int, name xml.Name) bool {
+		kind, _ := referenceKind(name)
+		return depth <= 2 || kind != ""
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", loc, err)
+	}
+
+	doc := &bundleDoc{loc: loc, data: data, elems: elems}
+	ext := ".xsd"
+	switch {
+	case len(elems) == 0:
+		return nil, fmt.Errorf("%s is empty", loc)
+	case elems[0].name == xml.Name{Space: wsdlNamespace, Local: "definitions"}:
+		doc.wsdl = true
+		ext = ".wsdl"
+	case elems[0].name != xml.Name{Space: xmlschema11, Local: "schema"}:
+		return nil, fmt.Errorf("%s is neither a WSDL nor an XML Schema", loc)
+	}
+	doc.name = b.name(loc, ext)
+	b.byLoc[loc.canonical()] = doc
+	b.docs = append(b.docs, doc)
+
+	for _, el := range elems[1:] {
+		kind, attr := referenceKind(el.name)
+		if kind == "" {
+			continue
+		}
+		ref := &bundleRef{el: el, kind: kind}
+		var location *Location
+		if el.attr(attr) == "" {
+			// Imports by namespace are resolved like the generator
+			// does: by a schema declaring the namespace, then by the
+			// catalogs.
+			mapped, ok := b.g.catalog.resolveNamespace(el.attr("namespace"))
+			if kind != "import" || !ok || b.provides(el.attr("namespace")) {
+				continue
+			}
+			if location, err = b.g.parseLocation(mapped); err != nil {
+				return nil, err
+			}
+			at := el.start + 1 + len(qualifiedName(data, el))
+			ref.attr, ref.value, ref.added = [2]int{at, at}, [2]int{at, at}, true
+		} else {
+			var ok bool
+			if ref.attr, ref.value, ok = attributeSpan(data, el, attr); !ok {
+				continue
+			}
+			if location, err = loc.Parse(el.attr(attr)); err != nil {
+				return nil, err
+			}
+			if mapped, ok := b.g.catalog.resolveURI(location.String()); ok {
+				if location, err = b.g.parseLocation(mapped); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if ref.doc, err = b.add(location); err != nil {
+			return nil, err
+		}
+		doc.refs = append(doc.refs, ref)
+	}
+	return doc, nil
+}
+
+// provides reports whether a schema of the bundle so far, inline in a WSDL
+// or not, declares namespace as its target namespace.
+func (b *bundler) provides(namespace string) bool {
+	for _, doc := range b.docs {
+		for _, el := range doc.elems {
+			if el.name == (xml.Name{Space: xmlschema11, Local: "schema"}) && el.attr("targetNamespace") == namespace {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// name returns a file name for the document at loc, unique in the bundle.
+func (b *bundler) name(loc *Location, ext string) string {
+	var base string
+	if loc.isURL() {
+		base = path.Base(loc.u.Path)
+		if loc.u.RawQuery != "" {
+			base += "_" + loc.u.RawQuery
+		}
+	} else {
+		base = path.Base(filepath.ToSlash(loc.String()))
+	}
+	base = strings.Trim(unsafeFileNameChars.ReplaceAllString(base, "_"), "_.")
+	if base == "" {
+		base = "schema"
+	}
+
+	stem := base
+	if e := strings.ToLower(path.Ext(base)); e == ".wsdl" || e == ".xsd" {
+		stem, ext = strings.TrimSuffix(base, path.Ext(base)), path.Ext(base)
+	}
+	name := stem + ext
+	for i := 2; b.names[strings.ToLower(name)]; i++ {
+		name = fmt.Sprintf("%s_%d%s", stem, i, ext)
+	}
+	b.names[strings.ToLower(name)] = true
+	return name
+}
+
+// referenceKind returns the kind of reference made by elements named name,
+// and the attribute holding the location of the referenced document.
+func referenceKind(name xml.Name) (kind, attr string) {
+	switch name.Space {
+	case wsdlNamespace:
+		if name.Local == "import" {
+			return "wsdl", "location"
+		}
+	case xmlschema11:
+		switch name.Local {
+		case "import", "include", "redefine", "override":
+			return name.Local, "schemaLocation"
+		}
+	}
+	return "", ""
+}
+
+// flattener collects the WSDLs and schemas merged into a flattened WSDL.
+type flattener struct {
+	wsdls   []*bundleDoc
+	schemas []*flatSchema
+	seen    map[string]bool
+}
+
+// flatSchema is a schema inlined in a flattened WSDL.
+type flatSchema struct {
+	doc *bundleDoc
+	el  *xmlElement
+	// namespace is adopted by a schema included without one of its own.
+	namespace string
+}
+
+func (f *flattener) addWSDL(doc *bundleDoc) {
+	if f.seen[doc.name] {
+		return
+	}
+	f.seen[doc.name] = true
+	f.wsdls = append(f.wsdls, doc)
+
+	for _, el := range doc.elems {
+		if el.depth == 2 && el.name == (xml.Name{Space: xmlschema11, Local: "schema"}) {
+			f.addSchema(doc, el, "")
+		}
+	}
+	for _, ref := range doc.refs {
+		if ref.kind != "wsdl" {
+			continue
+		}
+		if ref.doc.wsdl {
+			f.addWSDL(ref.doc)
+		} else {
+			f.addSchema(ref.doc, ref.doc.elems[0], "")
+		}
+	}
+}
+
+func (f *flattener) addSchema(doc *bundleDoc, el *xmlElement, namespace string) {
+	key := fmt.Sprintf("%s %d %s", doc.name, el.start, namespace)
+	if f.seen[key] {
+		return
+	}
+	f.seen[key] = true
+	s := &flatSchema{doc: doc, el: el, namespace: namespace}
+	f.schemas = append(f.schemas, s)
+
+	targetNamespace := el.attr("targetNamespace")
+	if targetNamespace == "" {
+		targetNamespace = namespace
+	}
+	for _, ref := range s.refs() {
+		root := ref.doc.elems[0]
+		switch {
+		case ref.kind == "include" && root.attr("targetNamespace") == "":
+			f.addSchema(ref.doc, root, targetNamespace)
+		default:
+			f.addSchema(ref.doc, root, "")
+		}
+	}
+}
+
+// refs returns the references made by the schema.
+func (s *flatSchema) refs() []*bundleRef {
+	var refs []*bundleRef
+	for _, ref := range s.doc.refs {
+		if ref.kind != "wsdl" && ref.el.start > s.el.start && ref.el.end <= s.el.end {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// render returns the schema, to be inlined where the namespaces of scope
+// are declared.
+func (s *flatSchema) render(scope map[string]string) []byte {
+	var edits []edit
+	for _, ref := range s.refs() {
+		if ref.kind == "include" {
+			edits = append(edits, edit{ref.el.start, ref.el.end, ""})
+		} else {
+			edits = append(edits, edit{ref.attr[0], ref.attr[1], ""})
+		}
+	}
+
+	// Unprefixed references of a chameleon schema designate the namespace
+	// it adopts.
+	declarations := declareNamespaces(s.doc.data, s.el, scope, s.namespace)
+	if s.namespace != "" {
+		declarations.text += fmt.Sprintf(" targetNamespace=%s", quoteAttr(s.namespace))
+	}
+	return splice(s.doc.data, s.el.start, s.el.end, append(edits, declarations))
+}
+
+// declareNamespaces returns the edit declaring, on the start tag of el, the
+// namespaces in scope where it was found that it would lose when moved to
+// where those of scope are. Without a default namespace of its own, el gets
+// defaultNamespace, or none.
+func declareNamespaces(data []byte, el *xmlElement, scope map[string]string, defaultNamespace string) edit {
+	own := el.declarations()
+	var prefixes []string
+	for prefix := range el.inherited {
+		if _, ok := own[prefix]; !ok && scope[prefix] != el.inherited[prefix] {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	sort.Strings(prefixes)
+
+	var text strings.Builder
+	if _, ok := el.scope()[""]; !ok && scope[""] != defaultNamespace {
+		fmt.Fprintf(&text, " xmlns=%s", quoteAttr(defaultNamespace))
+	}
+	for _, prefix := range prefixes {
+		if prefix == "" {
+			fmt.Fprintf(&text, " xmlns=%s", quoteAttr(el.inherited[prefix]))
+		} else {
+			fmt.Fprintf(&text, " xmlns:%s=%s", prefix, quoteAttr(el.inherited[prefix]))
+		}
+	}
+
+	at := el.start + 1 + len(qualifiedName(data, el))
+	return edit{at, at, text.String()}
+}
+
+func quoteAttr(value string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(value))
+	return `"` + b.String() + `"`
+}
+
+// xmlElement is an element of an XML document, located by offsets.
+type xmlElement struct {
+	name  xml.Name
+	attrs []xml.Attr
+	depth int
+	// start and end delimit the element, tagEnd its start tag.
+	start, tagEnd, end int
+	// inherited are the namespaces declared by the ancestors of the
+	// element, by prefix, the default namespace having none.
+	inherited map[string]string
+}
+
+func (el *xmlElement) attr(local string) string {
+	for _, attr := range el.attrs {
+		if attr.Name.Space == "" && attr.Name.Local == local {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// declarations returns the namespaces declared by the element itself.
+func (el *xmlElement) declarations() map[string]string {
+	declared := make(map[string]string)
+	for _, attr := range el.attrs {
+		switch {
+		case attr.Name.Space == "xmlns":
+			declared[attr.Name.Local] = attr.Value
+		case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+			declared[""] = attr.Value
+		}
+	}
+	return declared
+}
+
+// scope returns the namespaces in scope in the element, by prefix.
+func (el *xmlElement) scope() map[string]string {
+	scope := make(map[string]string)
+	for prefix, namespace := range el.inherited {
+		scope[prefix] = namespace
+	}
+	for prefix, namespace := range el.declarations() {
+		scope[prefix] = namespace
+	}
+	if scope[""] == "" {
+		delete(scope, "")
+	}
+	return scope
+}
+
+// scanElements returns the elements of data, in document order, for which
+// keep returns true.
+func scanElements(data []byte, keep func(depth int, name xml.Name) bool) ([]*xmlElement, error) {
+	var elems []*xmlElement
+	var open []*xmlElement
+	scopes := []map[string]string{{}}
+
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		start := int(d.InputOffset())
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			el := &xmlElement{
+				name:      t.Name,
+				attrs:     t.Attr,
+				depth:     len(open),
+				start:     start,
+				tagEnd:    int(d.InputOffset()),
+				inherited: scopes[len(scopes)-1],
+			}
+			if keep(el.depth, el.name) {
+				elems = append(elems, el)
+			}
+			scope := el.inherited
+			if declared := el.declarations(); len(declared) > 0 {
+				scope = el.scope()
+			}
+			open = append(open, el)
+			scopes = append(scopes, scope)
+		case xml.EndElement:
+			open[len(open)-1].end = int(d.InputOffset())
+			open = open[:len(open)-1]
+			scopes = scopes[:len(scopes)-1]
+		}
+	}
+	return elems, nil
+}
+
+// qualifiedName returns the name of el as written in its start tag.
+func qualifiedName(data []byte, el *xmlElement) string {
+	tag := data[el.start+1 : el.tagEnd]
+	if i := bytes.IndexAny(tag, " \t\r\n/>"); i >= 0 {
+		tag = tag[:i]
+	}
+	return string(tag)
+}
+
+// attributeSpan returns the spans of the attribute named local in the
+// start tag of el, with its leading space, and of its quoted value.
+func attributeSpan(data []byte, el *xmlElement, local string) (attr, value [2]int, ok bool) {
+	re := regexp.MustCompile(`\s` + regexp.QuoteMeta(local) + `\s*=\s*("[^"]*"|'[^']*')`)
+	m := re.FindSubmatchIndex(data[el.start:el.tagEnd])
+	if m == nil {
+		return attr, value, false
+	}
+	return [2]int{el.start + m[0], el.start + m[1]}, [2]int{el.start + m[2], el.start + m[3]}, true
+}
+
+// edit replaces the bytes between start and end with text.
+type edit struct {
+	start, end int
+	text       string
+}
+
+// splice returns data between from and to, with the edits applied. Edits
+// must not overlap.
+func splice(data []byte, from, to int, edits []edit) []byte {
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+
+	var b bytes.Buffer
+	at := from
+	for _, e := range edits {
+		if e.start < from || e.end > to {
+			continue
+		}
+		b.Write(data[at:e.start])
+		b.WriteString(e.text)
+		at = e.end
+	}
+	b.Write(data[at:to])
+	return b.Bytes()
+}
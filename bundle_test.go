@@ -0,0 +1,185 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestBundle(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"wsdl/Service.wsdl":          archiveServiceWSDL,
+		"wsdl/port/ServicePort.wsdl": archivePortWSDL,
+		"xsd/types.xsd":              downloadXSD,
+	} {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	generate := func(file string) map[string][]byte {
+		t.Helper()
+		g, err := NewGoWSDL(file, "myservice", false, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := g.Start()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	sortedLines := func(code []byte) string {
+		lines := strings.Split(string(code), "\n")
+		sort.Strings(lines)
+		return strings.Join(lines, "\n")
+	}
+
+	original := generate(filepath.Join(dir, "wsdl", "Service.wsdl"))
+
+	g, err := NewGoWSDL(filepath.Join(dir, "wsdl", "Service.wsdl"), "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := g.Bundle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bundle.Main != "Service.wsdl" || len(bundle.Files) != 3 {
+		t.Fatalf("unexpected bundle of %s with files %v", bundle.Main, bundle.Files)
+	}
+	if !strings.Contains(string(bundle.Files["ServicePort.wsdl"]), `schemaLocation="types.xsd"`) {
+		t.Errorf("schemaLocation not rewritten:\n%s", bundle.Files["ServicePort.wsdl"])
+	}
+
+	bundled := filepath.Join(dir, "bundle")
+	if err := bundle.Write(bundled); err != nil {
+		t.Fatal(err)
+	}
+	for _, kind := range []string{"types", "operations"} {
+		if got := generate(filepath.Join(bundled, bundle.Main)); string(got[kind]) != string(original[kind]) {
+			t.Errorf("%s of the bundle differ:\n%s\nwant:\n%s", kind, got[kind], original[kind])
+		}
+	}
+
+	flattened, err := g.Flatten()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(flattened), "<wsdl:import") || strings.Contains(string(flattened), "schemaLocation=") {
+		t.Errorf("flattened WSDL still refers to other documents:\n%s", flattened)
+	}
+	file := filepath.Join(dir, "flattened.wsdl")
+	if err := ioutil.WriteFile(file, flattened, 0644); err != nil {
+		t.Fatal(err)
+	}
+	got := generate(file)
+	for _, kind := range []string{"types", "operations"} {
+		if sortedLines(got[kind]) != sortedLines(original[kind]) {
+			t.Errorf("%s of the flattened WSDL differ:\n%s\nwant:\n%s", kind, got[kind], original[kind])
+		}
+	}
+}
+
+func TestBundleChameleon(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/chameleon/service.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := g.Bundle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := bundle.Write(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	original, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewGoWSDL(filepath.Join(dir, bundle.Main), "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := b.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got["types"]) != string(original["types"]) {
+		t.Errorf("types of the bundle differ:\n%s\nwant:\n%s", got["types"], original["types"])
+	}
+
+	if _, err := g.Flatten(); err == nil || !strings.Contains(err.Error(), "redefine") {
+		t.Errorf("got error %v, want one about the redefine", err)
+	}
+}
+
+func TestBundleCatalog(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/catalog/service.wsdl", "myservice", false, true, WithCatalogs("fixtures/catalog/catalog.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := g.Bundle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The schemas the catalog maps, by namespace or by location, are
+	// bundled; the sibling schema and the well-known namespace are not.
+	if len(bundle.Files) != 3 || bundle.Files["units.xsd"] == nil || bundle.Files["address.xsd"] == nil {
+		t.Fatalf("unexpected bundle of %s with files %v", bundle.Main, bundle.Files)
+	}
+	for _, expected := range []string{
+		`<xs:import schemaLocation="units.xsd" namespace="urn:example:units"/>`,
+		`<xs:import namespace="urn:example:address" schemaLocation="address.xsd"/>`,
+		`<xs:import namespace="urn:example:shipping:types"/>`,
+	} {
+		if !strings.Contains(string(bundle.Files[bundle.Main]), expected) {
+			t.Errorf("%s is missing in:\n%s", expected, bundle.Files[bundle.Main])
+		}
+	}
+	dir := t.TempDir()
+	if err := bundle.Write(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// The bundle generates the same code without the catalog.
+	original, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewGoWSDL(filepath.Join(dir, bundle.Main), "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := b.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got["types"]) != string(original["types"]) {
+		t.Errorf("types of the bundle differ:\n%s\nwant:\n%s", got["types"], original["types"])
+	}
+
+	// So does the flattened WSDL, which inlines the schema mapped by
+	// namespace too.
+	flattened, err := g.Flatten()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(flattened), `name="Unit"`) || strings.Contains(string(flattened), "schemaLocation=") {
+		t.Errorf("unexpected flattened WSDL:\n%s", flattened)
+	}
+}
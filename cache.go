@@ -0,0 +1,295 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultCacheDir is where downloaded WSDL and XSD documents are cached
+// unless configured otherwise: gowsdl in the cache directory of the user, or
+// in the temporary directory when the user has none.
+var DefaultCacheDir = defaultCacheDir()
+
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "gowsdl-cache")
+	}
+	return filepath.Join(dir, "gowsdl")
+}
+
+// Cache stores downloaded WSDL and XSD documents on disk, keyed by URL and
+// by the credentials they were downloaded with, so that documents are only
+// served to runs sending the same ones. Cached documents are revalidated
+// with the server using their ETag and Last-Modified headers, so they are
+// only downloaded again once changed.
+//
+// The cache directory must belong to the current user and must not be
+// writable by others, who could otherwise plant documents in it.
+type Cache struct {
+	// Dir is the directory holding the cache entries.
+	Dir string
+
+	// TTL is how long a document is used without revalidation after it
+	// was downloaded or revalidated. Zero revalidates on every use.
+	TTL time.Duration
+
+	// Offline serves documents from the cache only. Documents that are
+	// not cached fail to load instead of being downloaded.
+	Offline bool
+}
+
+// CacheEntry describes a cached document.
+type CacheEntry struct {
+	URL string `json:"url"`
+	// Credentials identifies the credentials the document was downloaded
+	// with, without revealing them. It is empty for anonymous downloads.
+	Credentials  string    `json:"credentials,omitempty"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	Validated    time.Time `json:"validated"`
+	Size         int64     `json:"size"`
+
+	// Err is why the entry cannot be read, in which case the other fields
+	// are empty. Purge removes such entries as well.
+	Err error `json:"-"`
+
+	// file is the file holding the entry.
+	file string
+}
+
+// Entries returns the entries of the cache, sorted by URL. Entries that
+// cannot be read come last, with Err set.
+func (c *Cache) Entries() ([]*CacheEntry, error) {
+	if err := c.checkDir(); err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(c.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries, invalid []*CacheEntry
+	for _, file := range files {
+		entry, err := readCacheEntry(file)
+		if err != nil {
+			invalid = append(invalid, &CacheEntry{Err: fmt.Errorf("invalid cache entry %s: %v", file, err), file: file})
+			continue
+		}
+		entry.file = file
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].URL < entries[j].URL })
+	return append(entries, invalid...), nil
+}
+
+// Remove removes the document downloaded from url from the cache, whatever
+// the credentials it was downloaded with.
+func (c *Cache) Remove(url string) error {
+	entries, err := c.Entries()
+	if err != nil {
+		return err
+	}
+	removed := false
+	for _, entry := range entries {
+		if entry.Err != nil || entry.URL != url {
+			continue
+		}
+		if err := c.remove(entry); err != nil {
+			return err
+		}
+		removed = true
+	}
+	if !removed {
+		return fmt.Errorf("%s is not cached", url)
+	}
+	return nil
+}
+
+// Purge removes all the documents from the cache.
+func (c *Cache) Purge() error {
+	entries, err := c.Entries()
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := c.remove(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remove removes the files of entry.
+func (c *Cache) remove(entry *CacheEntry) error {
+	meta := entry.file
+	data := strings.TrimSuffix(meta, ".json") + ".data"
+	if err := os.Remove(meta); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(data); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// paths returns the files holding the entry and the content cached for url
+// downloaded with the given credentials.
+func (c *Cache) paths(url, credentials string) (meta, data string) {
+	key := url
+	if credentials != "" {
+		key += "\x00" + credentials
+	}
+	sum := sha256.Sum256([]byte(key))
+	file := filepath.Join(c.Dir, hex.EncodeToString(sum[:]))
+	return file + ".json", file + ".data"
+}
+
+// checkDir checks that the cache directory, if it exists, is a directory
+// of the current user that others cannot write to.
+func (c *Cache) checkDir() error {
+	info, err := os.Lstat(c.Dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("cache directory %s is not a directory", c.Dir)
+	}
+	if info.Mode().Perm()&0022 != 0 {
+		return fmt.Errorf("cache directory %s is writable by other users", c.Dir)
+	}
+	if !ownedByCurrentUser(info) {
+		return fmt.Errorf("cache directory %s belongs to another user", c.Dir)
+	}
+	return nil
+}
+
+func readCacheEntry(file string) (*CacheEntry, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	entry := new(CacheEntry)
+	if err := json.Unmarshal(b, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// load returns the entry and the content cached for url downloaded with
+// the given credentials, if any.
+func (c *Cache) load(url, credentials string) (*CacheEntry, []byte, bool) {
+	meta, data := c.paths(url, credentials)
+	entry, err := readCacheEntry(meta)
+	if err != nil {
+		return nil, nil, false
+	}
+	content, err := ioutil.ReadFile(data)
+	if err != nil || entry.URL != url || entry.Credentials != credentials {
+		return nil, nil, false
+	}
+	return entry, content, true
+}
+
+// store caches content downloaded from url with entry.
+func (c *Cache) store(entry *CacheEntry, content []byte) error {
+	if err := os.MkdirAll(c.Dir, 0700); err != nil {
+		return err
+	}
+	if err := c.checkDir(); err != nil {
+		return err
+	}
+
+	meta, data := c.paths(entry.URL, entry.Credentials)
+	entry.Size = int64(len(content))
+	b, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(data, content, 0600); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(meta, b, 0600)
+}
+
+// fetch returns the document at url, from the cache when it is fresh or
+// still valid according to the server, using get to send requests with the
+// credentials identified by credentials. A stale document is served when
+// the server cannot be reached or fails, which is reported with warnf like
+// failures to cache documents.
+func (c *Cache) fetch(url, credentials string, get func(url string, header http.Header) (*http.Response, error),
+	warnf func(format string, args ...interface{})) ([]byte, error) {
+	if err := c.checkDir(); err != nil {
+		return nil, err
+	}
+
+	entry, content, cached := c.load(url, credentials)
+	if cached && (c.Offline || time.Since(entry.Validated) < c.TTL) {
+		return content, nil
+	}
+	if c.Offline {
+		return nil, fmt.Errorf("%s is not cached, and downloads are disabled in offline mode", url)
+	}
+
+	header := make(http.Header)
+	if cached {
+		if entry.ETag != "" {
+			header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	resp, err := get(url, header)
+	if err != nil {
+		if cached {
+			warnf("Using the cached copy of %s: %v", url, err)
+			return content, nil
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached:
+		entry.Validated = time.Now()
+	case resp.StatusCode == http.StatusOK:
+		if content, err = ioutil.ReadAll(resp.Body); err != nil {
+			return nil, err
+		}
+		entry = &CacheEntry{
+			URL:          url,
+			Credentials:  credentials,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			Validated:    time.Now(),
+		}
+	case resp.StatusCode >= 500 && cached:
+		warnf("Using the cached copy of %s: received response code %d", url, resp.StatusCode)
+		return content, nil
+	default:
+		return nil, fmt.Errorf("Received response code %d", resp.StatusCode)
+	}
+
+	if err := c.store(entry, content); err != nil {
+		warnf("Unable to cache %s: %v", url, err)
+	}
+	return content, nil
+}
@@ -0,0 +1,169 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	wsdl, err := ioutil.ReadFile("fixtures/test.wsdl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var downloads, revalidations int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			revalidations++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", `"v1"`)
+		w.Write(wsdl)
+	}))
+	defer server.Close()
+
+	cache := &Cache{Dir: t.TempDir()}
+	generate := func() error {
+		g, err := NewGoWSDL(server.URL+"/test.wsdl", "myservice", false, true, WithCache(cache))
+		if err != nil {
+			return err
+		}
+		_, err = g.Start()
+		return err
+	}
+
+	// Downloaded, then revalidated.
+	for i := 0; i < 2; i++ {
+		if err := generate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if downloads != 1 || revalidations != 1 {
+		t.Errorf("got %d downloads and %d revalidations, want 1 and 1", downloads, revalidations)
+	}
+
+	// Fresh within the TTL.
+	cache.TTL = time.Hour
+	if err := generate(); err != nil {
+		t.Fatal(err)
+	}
+	if downloads != 1 || revalidations != 1 {
+		t.Errorf("got %d downloads and %d revalidations within the TTL, want 1 and 1", downloads, revalidations)
+	}
+
+	// Served from the cache only, even when stale.
+	cache.TTL = 0
+	cache.Offline = true
+	if err := generate(); err != nil {
+		t.Fatal(err)
+	}
+	if downloads != 1 || revalidations != 1 {
+		t.Errorf("got %d downloads and %d revalidations offline, want 1 and 1", downloads, revalidations)
+	}
+
+	entries, err := cache.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].URL != server.URL+"/test.wsdl" || entries[0].ETag != `"v1"` || entries[0].Size != int64(len(wsdl)) {
+		t.Fatalf("unexpected cache entries %+v", entries)
+	}
+
+	// Entries that cannot be read are listed last, and purged too.
+	invalid := filepath.Join(cache.Dir, "invalid.json")
+	if err := ioutil.WriteFile(invalid, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	entries, err = cache.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[1].Err == nil || !strings.Contains(entries[1].Err.Error(), invalid) {
+		t.Fatalf("unexpected cache entries %+v", entries)
+	}
+
+	if err := cache.Purge(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(invalid); !os.IsNotExist(err) {
+		t.Errorf("invalid cache entry was not purged: %v", err)
+	}
+	if err := generate(); err == nil {
+		t.Error("expected offline generation to fail once the cache is purged")
+	}
+}
+
+func TestCacheSafety(t *testing.T) {
+	wsdl, err := ioutil.ReadFile("fixtures/test.wsdl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failing := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(wsdl)
+	}))
+	defer server.Close()
+
+	cache := &Cache{Dir: filepath.Join(t.TempDir(), "cache")}
+	var g *GoWSDL
+	generate := func(opts DownloadOptions) error {
+		g, err = NewGoWSDL(server.URL+"/test.wsdl", "myservice", false, true, WithCache(cache), WithDownloadOptions(opts))
+		if err != nil {
+			return err
+		}
+		_, err = g.Start()
+		return err
+	}
+	token := DownloadOptions{BearerToken: "token"}
+
+	// Documents downloaded with credentials are not served without them.
+	if err := generate(token); err != nil {
+		t.Fatal(err)
+	}
+	cache.Offline = true
+	if err := generate(DownloadOptions{}); err == nil {
+		t.Error("a document downloaded with credentials was served without them")
+	}
+	if err := generate(DownloadOptions{BearerToken: "other"}); err == nil {
+		t.Error("a document downloaded with credentials was served with other ones")
+	}
+	if err := generate(token); err != nil {
+		t.Errorf("a document downloaded with credentials was not served with them: %v", err)
+	}
+
+	// Stale documents are served when the server fails.
+	cache.Offline = false
+	failing = true
+	if err := generate(token); err != nil {
+		t.Errorf("the cached document was not served when the server failed: %v", err)
+	}
+	want := server.URL + "/test.wsdl: warning: Using the cached copy of " + server.URL + "/test.wsdl: received response code 503"
+	if diagnostics := g.Diagnostics(); len(diagnostics) != 1 || diagnostics[0].String() != want {
+		t.Errorf("got diagnostics %v, want %q", diagnostics, want)
+	}
+
+	// Others must not be able to plant documents in the cache.
+	if err := os.Chmod(cache.Dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := generate(token); err == nil || !strings.Contains(err.Error(), "writable by other users") {
+		t.Errorf("got error %v for a cache directory writable by others", err)
+	}
+}
@@ -0,0 +1,234 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const catalogNamespace = "urn:oasis:names:tc:entity:xmlns:xml:catalog"
+
+// wellKnownNamespaces are imported by many schemas without a location.
+// Their types are built into the generator, so they need no schema.
+var wellKnownNamespaces = map[string]bool{
+	"http://www.w3.org/XML/1998/namespace":      true,
+	"http://www.w3.org/2001/XMLSchema":          true,
+	"http://www.w3.org/2001/XMLSchema-instance": true,
+	"http://schemas.xmlsoap.org/soap/encoding/": true,
+	"http://schemas.xmlsoap.org/wsdl/":          true,
+}
+
+// catalogEntry maps a namespace, system identifier or URI to a location.
+type catalogEntry struct {
+	// kind is the name of the catalog element: uri, system, public,
+	// rewriteURI, rewriteSystem, uriSuffix or systemSuffix.
+	kind string
+	// match is the name, identifier, prefix or suffix to match.
+	match string
+	// target is the absolute location, or location prefix for rewrites.
+	target string
+}
+
+// catalog is an OASIS XML Catalog, along with the catalogs it delegates
+// to through nextCatalog, in the order they are consulted.
+type catalog struct {
+	files [][]catalogEntry
+}
+
+// loadCatalogs reads the catalog files at the given locations, and the
+// ones they chain to.
+func (g *GoWSDL) loadCatalogs(locations []string) (*catalog, error) {
+	c := new(catalog)
+	loaded := make(map[string]bool)
+
+	var load func(loc *Location) error
+	load = func(loc *Location) error {
+		if loaded[loc.canonical()] {
+			return nil
+		}
+		loaded[loc.canonical()] = true
+
+		data, err := g.fetchFile(loc)
+		if err != nil {
+			return err
+		}
+		entries, next, err := parseCatalog(data, loc)
+		if err != nil {
+			return fmt.Errorf("catalog %s: %v", loc, err)
+		}
+		c.files = append(c.files, entries)
+		for _, n := range next {
+			if err := load(n); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, location := range locations {
+		loc, err := g.parseLocation(location)
+		if err != nil {
+			return nil, err
+		}
+		if err := load(loc); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// parseCatalog returns the entries of the catalog document data found at
+// loc, and the locations of the catalogs it chains to. Relative locations
+// are resolved against loc, or the xml:base in effect.
+func parseCatalog(data []byte, loc *Location) ([]catalogEntry, []*Location, error) {
+	var entries []catalogEntry
+	var next []*Location
+
+	bases := []*Location{loc}
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			base := bases[len(bases)-1]
+			attrs := make(map[string]string)
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "http://www.w3.org/XML/1998/namespace" && attr.Name.Local == "base" {
+					if base, err = base.Parse(attr.Value); err != nil {
+						return nil, nil, err
+					}
+					continue
+				}
+				attrs[attr.Name.Local] = attr.Value
+			}
+			bases = append(bases, base)
+
+			if t.Name.Space != catalogNamespace {
+				continue
+			}
+
+			resolve := func(ref string) (string, error) {
+				target, err := base.Parse(ref)
+				if err != nil {
+					return "", err
+				}
+				return target.String(), nil
+			}
+
+			var matchAttr, targetAttr string
+			switch t.Name.Local {
+			case "uri":
+				matchAttr, targetAttr = "name", "uri"
+			case "system":
+				matchAttr, targetAttr = "systemId", "uri"
+			case "public":
+				matchAttr, targetAttr = "publicId", "uri"
+			case "rewriteURI":
+				matchAttr, targetAttr = "uriStartString", "rewritePrefix"
+			case "rewriteSystem":
+				matchAttr, targetAttr = "systemIdStartString", "rewritePrefix"
+			case "uriSuffix":
+				matchAttr, targetAttr = "uriSuffix", "uri"
+			case "systemSuffix":
+				matchAttr, targetAttr = "systemIdSuffix", "uri"
+			case "nextCatalog":
+				n, err := base.Parse(attrs["catalog"])
+				if err != nil {
+					return nil, nil, err
+				}
+				next = append(next, n)
+				continue
+			default:
+				continue
+			}
+
+			target, err := resolve(attrs[targetAttr])
+			if err != nil {
+				return nil, nil, err
+			}
+			entries = append(entries, catalogEntry{kind: t.Name.Local, match: attrs[matchAttr], target: target})
+		case xml.EndElement:
+			bases = bases[:len(bases)-1]
+		}
+	}
+	return entries, next, nil
+}
+
+// resolveNamespace returns the location of the schema for namespace, as
+// mapped by uri or public entries.
+func (c *catalog) resolveNamespace(namespace string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	for _, entries := range c.files {
+		for _, kind := range []string{"uri", "public"} {
+			for _, e := range entries {
+				if e.kind == kind && e.match == namespace {
+					return e.target, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// resolveURI returns the location mapped to uri, looking at URI entries
+// first and system identifier entries next. Exact matches win over
+// rewrites, and rewrites over suffixes; the longest prefix or suffix wins.
+func (c *catalog) resolveURI(uri string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	for _, entries := range c.files {
+		for _, kinds := range [][3]string{
+			{"uri", "rewriteURI", "uriSuffix"},
+			{"system", "rewriteSystem", "systemSuffix"},
+		} {
+			if target, ok := lookupCatalogEntries(entries, kinds, uri); ok {
+				return target, true
+			}
+		}
+	}
+	return "", false
+}
+
+func lookupCatalogEntries(entries []catalogEntry, kinds [3]string, uri string) (string, bool) {
+	for _, e := range entries {
+		if e.kind == kinds[0] && e.match == uri {
+			return e.target, true
+		}
+	}
+
+	var best *catalogEntry
+	for i, e := range entries {
+		if e.kind == kinds[1] && strings.HasPrefix(uri, e.match) && (best == nil || len(e.match) > len(best.match)) {
+			best = &entries[i]
+		}
+	}
+	if best != nil {
+		return best.target + strings.TrimPrefix(uri, best.match), true
+	}
+
+	for i, e := range entries {
+		if e.kind == kinds[2] && strings.HasSuffix(uri, e.match) && (best == nil || len(e.match) > len(best.match)) {
+			best = &entries[i]
+		}
+	}
+	if best != nil {
+		return best.target, true
+	}
+	return "", false
+}
@@ -0,0 +1,88 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCatalogResolveURI(t *testing.T) {
+	loc, err := ParseLocation("/schemas/catalog.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, next, err := parseCatalog([]byte(`<catalog xmlns="urn:oasis:names:tc:entity:xmlns:xml:catalog">
+  <uri name="http://example.org/a.xsd" uri="a.xsd"/>
+  <rewriteURI uriStartString="http://example.org/" rewritePrefix="mirror/"/>
+  <rewriteURI uriStartString="http://example.org/deep/" rewritePrefix="deep/"/>
+  <systemSuffix systemIdSuffix="/b.xsd" uri="b.xsd"/>
+  <group xml:base="/other/">
+    <uri name="urn:example" uri="ns.xsd"/>
+  </group>
+  <nextCatalog catalog="next.xml"/>
+</catalog>`), loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &catalog{files: [][]catalogEntry{entries}}
+
+	for uri, expected := range map[string]string{
+		"http://example.org/a.xsd":        "/schemas/a.xsd",
+		"http://example.org/c/d.xsd":      "/schemas/mirror/c/d.xsd",
+		"http://example.org/deep/e.xsd":   "/schemas/deep/e.xsd",
+		"http://elsewhere.example/b.xsd":  "/schemas/b.xsd",
+		"http://elsewhere.example/no.xsd": "",
+	} {
+		actual, _ := c.resolveURI(uri)
+		if actual != filepath.FromSlash(expected) {
+			t.Errorf("%s: got %q want %q", uri, actual, expected)
+		}
+	}
+
+	if actual, _ := c.resolveNamespace("urn:example"); actual != filepath.FromSlash("/other/ns.xsd") {
+		t.Errorf("got %q want /other/ns.xsd", actual)
+	}
+	if len(next) != 1 || next[0].String() != filepath.FromSlash("/schemas/next.xml") {
+		t.Errorf("got next catalogs %v", next)
+	}
+}
+
+func TestCatalogImports(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/catalog/service.wsdl", "myservice", false, true, WithCatalogs("fixtures/catalog/catalog.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := typeCheck(resp); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"Weight", "Unit", "Address"} {
+		if _, err := getTypeDeclaration(resp, name); err != nil {
+			t.Error(err)
+		}
+	}
+
+	graph := g.SchemaGraph().String()
+	for _, expected := range []string{
+		"import  (urn:example:shipping:types) -> ",
+		filepath.FromSlash("fixtures/catalog/local/units.xsd"),
+		"import http://schemas.example.org/address.xsd (urn:example:address) -> ",
+		"well-known namespace",
+	} {
+		if !strings.Contains(graph, expected) {
+			t.Errorf("%q is missing in graph:\n%s", expected, graph)
+		}
+	}
+	if diagnostics := g.Diagnostics(); len(diagnostics) > 0 {
+		t.Errorf("unexpected diagnostics %v", diagnostics)
+	}
+}
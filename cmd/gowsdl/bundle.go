@@ -0,0 +1,62 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	gen "github.com/hooklift/gowsdl"
+)
+
+// bundleCommand runs the bundle subcommand, storing a WSDL and the documents
+// it refers to in a directory. It accepts the download options of the main
+// command.
+func bundleCommand(args []string) {
+	flatten := flag.Bool("flatten", false, "Also write a single WSDL, flattened.wsdl, with all the schemas inlined")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s bundle [options] myservice.wsdl dir\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.CommandLine.Parse(args)
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	wsdlPath, dir := flag.Arg(0), flag.Arg(1)
+
+	opts, err := flagConfig("").loadOptions()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	gowsdl, err := gen.New(wsdlPath, opts...)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	bundle, err := gowsdl.Bundle()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err := bundle.Write(dir); err != nil {
+		log.Fatalln(err)
+	}
+	log.Printf("Wrote %d files to %s, starting with %s", len(bundle.Files), dir, bundle.Main)
+
+	if *flatten {
+		flattened, err := gowsdl.Flatten()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "flattened.wsdl"), flattened, 0644); err != nil {
+			log.Fatalln(err)
+		}
+		log.Println("Wrote", filepath.Join(dir, "flattened.wsdl"))
+	}
+}
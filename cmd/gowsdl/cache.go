@@ -0,0 +1,64 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	gen "github.com/hooklift/gowsdl"
+)
+
+// cacheCommand runs the cache subcommand, listing or purging the documents
+// cached by previous runs.
+func cacheCommand(args []string) {
+	flags := flag.NewFlagSet("cache", flag.ExitOnError)
+	cacheDir := flags.String("cache-dir", gen.DefaultCacheDir, "Directory of the cache of downloaded WSDL and XSD documents")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s cache [options] list\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s cache [options] purge [url...]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	cache := &gen.Cache{Dir: *cacheDir}
+	switch flags.Arg(0) {
+	case "list":
+		entries, err := cache.Entries()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "URL\tSIZE\tVALIDATED\tETAG\tLAST-MODIFIED")
+		for _, entry := range entries {
+			if entry.Err != nil {
+				log.Println("[WARN]", entry.Err)
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", entry.URL, entry.Size,
+				entry.Validated.Format(time.RFC3339), entry.ETag, entry.LastModified)
+		}
+		w.Flush()
+	case "purge":
+		if flags.NArg() == 1 {
+			if err := cache.Purge(); err != nil {
+				log.Fatalln(err)
+			}
+			return
+		}
+		for _, url := range flags.Args()[1:] {
+			if err := cache.Remove(url); err != nil {
+				log.Fatalln(err)
+			}
+		}
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+}
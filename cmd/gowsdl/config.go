@@ -0,0 +1,375 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	gen "github.com/hooklift/gowsdl"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigs are the configuration files read when gowsdl is run without
+// arguments.
+var defaultConfigs = []string{"gowsdl.yaml", "gowsdl.yml", "gowsdl.json"}
+
+// config describes how code is generated from one or more WSDLs. It is read
+// from a YAML or JSON file, and the command line flags are turned into one
+// too. Relative paths are relative to the directory of the file.
+type config struct {
+	Download downloadConfig `yaml:"download" json:"download"`
+	Cache    cacheConfig    `yaml:"cache" json:"cache"`
+	// Catalogs are OASIS XML Catalog files used to find imported schemas
+	// locally.
+	Catalogs []string      `yaml:"catalogs" json:"catalogs"`
+	Inputs   []inputConfig `yaml:"inputs" json:"inputs"`
+}
+
+type downloadConfig struct {
+	// User is the user and password, as user:password, for basic
+	// authentication.
+	User  string `yaml:"user" json:"user"`
+	Token string `yaml:"token" json:"token"`
+	// Headers are additional headers, as "Name: value".
+	Headers  []string `yaml:"headers" json:"headers"`
+	Cert     string   `yaml:"cert" json:"cert"`
+	Key      string   `yaml:"key" json:"key"`
+	CAFile   string   `yaml:"caFile" json:"caFile"`
+	Proxy    string   `yaml:"proxy" json:"proxy"`
+	Insecure bool     `yaml:"insecure" json:"insecure"`
+	// CredentialOrigins are the origins credentials and headers are sent
+	// to besides the one of the WSDL.
+	CredentialOrigins []string `yaml:"credentialOrigins" json:"credentialOrigins"`
+}
+
+type cacheConfig struct {
+	Dir      string `yaml:"dir" json:"dir"`
+	TTL      string `yaml:"ttl" json:"ttl"`
+	Offline  bool   `yaml:"offline" json:"offline"`
+	Disabled bool   `yaml:"disabled" json:"disabled"`
+}
+
+// inputConfig describes the code generated from a WSDL.
+type inputConfig struct {
+	WSDL string `yaml:"wsdl" json:"wsdl"`
+	// Dir is the directory the directory of the package is created in.
+	Dir     string `yaml:"dir" json:"dir"`
+	Package string `yaml:"package" json:"package"`
+	File    string `yaml:"file" json:"file"`
+
+	// Namespaces maps target namespaces to the import paths of the Go
+	// packages their types are taken from instead of being generated.
+	Namespaces map[string]string `yaml:"namespaces" json:"namespaces"`
+	// Types maps XSD types to Go types.
+	Types map[string]typeConfig `yaml:"types" json:"types"`
+
+	Naming      namingConfig      `yaml:"naming" json:"naming"`
+	Occurrences occurrencesConfig `yaml:"occurrences" json:"occurrences"`
+	Operations  operationsConfig  `yaml:"operations" json:"operations"`
+	Filters     []string          `yaml:"filters" json:"filters"`
+	Templates   string            `yaml:"templates" json:"templates"`
+	Unexported  bool              `yaml:"unexported" json:"unexported"`
+	Unknown     bool              `yaml:"unknownFields" json:"unknownFields"`
+	Strict      bool              `yaml:"strict" json:"strict"`
+	Client      *bool             `yaml:"client" json:"client"`
+	Server      *bool             `yaml:"server" json:"server"`
+	Split       bool              `yaml:"split" json:"split"`
+	MaxTypes    int               `yaml:"maxTypesSize" json:"maxTypesSize"`
+	Overwrite   string            `yaml:"overwrite" json:"overwrite"`
+	IR          string            `yaml:"ir" json:"ir"`
+	Plugin      string            `yaml:"plugin" json:"plugin"`
+	PluginOpt   string            `yaml:"pluginOpt" json:"pluginOpt"`
+	SchemaGraph bool              `yaml:"schemaGraph" json:"schemaGraph"`
+}
+
+type namingConfig struct {
+	Style       string            `yaml:"style" json:"style"`
+	Initialisms []string          `yaml:"initialisms" json:"initialisms"`
+	InlineTypes string            `yaml:"inlineTypes" json:"inlineTypes"`
+	Types       map[string]string `yaml:"types" json:"types"`
+	Fields      map[string]string `yaml:"fields" json:"fields"`
+	Operations  map[string]string `yaml:"operations" json:"operations"`
+}
+
+type typeConfig struct {
+	Type   string `yaml:"type" json:"type"`
+	Import string `yaml:"import" json:"import"`
+}
+
+type occurrencesConfig struct {
+	OptionalPointers bool `yaml:"optionalPointers" json:"optionalPointers"`
+	RequiredValues   bool `yaml:"requiredValues" json:"requiredValues"`
+}
+
+// operationsConfig selects the operations code is generated for, as
+// gen.Operations does.
+type operationsConfig struct {
+	Include []string `yaml:"include" json:"include"`
+	Exclude []string `yaml:"exclude" json:"exclude"`
+}
+
+// generation is a WSDL code is generated from, and how.
+type generation struct {
+	wsdl        string
+	dir         string
+	pkg         string
+	file        string
+	opts        []gen.Option
+	overwrite   gen.Overwrite
+	irFile      string
+	plugin      string
+	pluginOpt   string
+	schemaGraph bool
+}
+
+// readConfig reads a configuration file, in JSON if it has the .json
+// extension, in YAML otherwise. Unknown keys are errors.
+func readConfig(file string) (*config, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	c := new(config)
+	if filepath.Ext(file) == ".json" {
+		d := json.NewDecoder(bytes.NewReader(data))
+		d.DisallowUnknownFields()
+		err = d.Decode(c)
+	} else {
+		d := yaml.NewDecoder(bytes.NewReader(data))
+		d.KnownFields(true)
+		err = d.Decode(c)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	if len(c.Inputs) == 0 {
+		return nil, fmt.Errorf("%s: no inputs", file)
+	}
+	c.resolvePaths(filepath.Dir(file))
+	return c, nil
+}
+
+// resolvePaths makes the relative paths of c relative to base.
+func (c *config) resolvePaths(base string) {
+	rel := func(p *string) {
+		if *p != "" && !filepath.IsAbs(*p) && !strings.Contains(*p, "://") {
+			*p = filepath.Join(base, *p)
+		}
+	}
+	rel(&c.Download.Cert)
+	rel(&c.Download.Key)
+	rel(&c.Download.CAFile)
+	rel(&c.Cache.Dir)
+	for i := range c.Catalogs {
+		rel(&c.Catalogs[i])
+	}
+	for i := range c.Inputs {
+		in := &c.Inputs[i]
+		rel(&in.WSDL)
+		rel(&in.Dir)
+		rel(&in.Templates)
+		rel(&in.IR)
+		if strings.ContainsAny(in.Plugin, `/\`) {
+			rel(&in.Plugin)
+		}
+	}
+}
+
+// loadOptions returns the options configuring how the WSDLs and the
+// documents they refer to are loaded.
+func (c *config) loadOptions() ([]gen.Option, error) {
+	var opts []gen.Option
+	download := gen.DownloadOptions{
+		BearerToken: c.Download.Token,
+		ClientCert:  c.Download.Cert,
+		ClientKey:   c.Download.Key,
+		CAFile:      c.Download.CAFile,
+		ProxyURL:    c.Download.Proxy,
+		Header:      make(http.Header),
+
+		CredentialOrigins: c.Download.CredentialOrigins,
+	}
+	if user := c.Download.User; user != "" {
+		download.Username = user
+		if i := strings.Index(user, ":"); i >= 0 {
+			download.Username, download.Password = user[:i], user[i+1:]
+		}
+	}
+	for _, header := range c.Download.Headers {
+		i := strings.Index(header, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("header %q is not in the Name: value form", header)
+		}
+		download.Header.Add(strings.TrimSpace(header[:i]), strings.TrimSpace(header[i+1:]))
+	}
+	opts = append(opts, gen.WithDownloadOptions(download))
+	if c.Download.Insecure {
+		opts = append(opts, gen.WithIgnoreTLS())
+	}
+
+	if c.Cache.Offline && c.Cache.Disabled {
+		return nil, errors.New("offline needs the cache, it cannot be used with the cache disabled")
+	}
+	if !c.Cache.Disabled {
+		cache := &gen.Cache{Dir: c.Cache.Dir, Offline: c.Cache.Offline}
+		if cache.Dir == "" {
+			cache.Dir = gen.DefaultCacheDir
+		}
+		if c.Cache.TTL != "" {
+			ttl, err := time.ParseDuration(c.Cache.TTL)
+			if err != nil {
+				return nil, fmt.Errorf("cache ttl: %v", err)
+			}
+			cache.TTL = ttl
+		}
+		opts = append(opts, gen.WithCache(cache))
+	}
+	if len(c.Catalogs) > 0 {
+		opts = append(opts, gen.WithCatalogs(c.Catalogs...))
+	}
+	return opts, nil
+}
+
+// generations returns what code to generate from which WSDL.
+func (c *config) generations() ([]*generation, error) {
+	loaded, err := c.loadOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	var gens []*generation
+	for _, in := range c.Inputs {
+		g, err := in.generation(loaded)
+		if err != nil {
+			return nil, fmt.Errorf("input %s: %v", in.WSDL, err)
+		}
+		gens = append(gens, g)
+	}
+	return gens, nil
+}
+
+func (in *inputConfig) generation(loaded []gen.Option) (*generation, error) {
+	if in.WSDL == "" {
+		return nil, errors.New("wsdl is required")
+	}
+
+	g := &generation{
+		wsdl:        in.WSDL,
+		dir:         in.Dir,
+		pkg:         in.Package,
+		file:        in.File,
+		irFile:      in.IR,
+		plugin:      in.Plugin,
+		pluginOpt:   in.PluginOpt,
+		schemaGraph: in.SchemaGraph,
+	}
+	if g.dir == "" {
+		g.dir = "."
+	}
+	if g.pkg == "" {
+		g.pkg = "myservice"
+	}
+	if g.file == "" {
+		g.file = g.pkg + ".go"
+	}
+	if g.file == g.wsdl {
+		return nil, errors.New("output file cannot be the same WSDL file")
+	}
+
+	var err error
+	if g.overwrite, err = gen.ParseOverwrite(in.Overwrite); err != nil {
+		return nil, err
+	}
+	if in.MaxTypes < 0 {
+		return nil, errors.New("maxTypesSize cannot be negative")
+	}
+
+	opts := append([]gen.Option{}, loaded...)
+	opts = append(opts, gen.WithLayout(gen.Layout{
+		Split:        in.Split,
+		MaxTypesSize: in.MaxTypes,
+		NoClient:     in.Client != nil && !*in.Client,
+		NoServer:     in.Server != nil && !*in.Server,
+	}))
+	style, err := gen.ParseNamingStyle(in.Naming.Style)
+	if err != nil {
+		return nil, err
+	}
+	inline, err := gen.ParseInlineTypeNaming(in.Naming.InlineTypes)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, gen.WithNaming(gen.Naming{
+		Style:       style,
+		Initialisms: in.Naming.Initialisms,
+		Types:       in.Naming.Types,
+		Fields:      in.Naming.Fields,
+		Operations:  in.Naming.Operations,
+		InlineTypes: inline,
+	}))
+	if len(in.Types) > 0 {
+		types := make(map[string]gen.GoType)
+		for xsdType, t := range in.Types {
+			types[xsdType] = gen.GoType{Type: t.Type, Import: t.Import}
+		}
+		opts = append(opts, gen.WithTypes(types))
+	}
+	if len(in.Namespaces) > 0 {
+		opts = append(opts, gen.WithNamespaces(in.Namespaces))
+	}
+	opts = append(opts, gen.WithOccurrences(gen.Occurrences{
+		OptionalPointers: in.Occurrences.OptionalPointers,
+		RequiredValues:   in.Occurrences.RequiredValues,
+	}))
+
+	if len(in.Operations.Include) > 0 || len(in.Operations.Exclude) > 0 {
+		opts = append(opts, gen.WithOperations(gen.Operations{
+			Include: in.Operations.Include,
+			Exclude: in.Operations.Exclude,
+		}))
+	}
+	for _, name := range in.Filters {
+		filter, err := gen.ParseFilter(name)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, gen.WithFilters(filter))
+	}
+	if in.Templates != "" {
+		opts = append(opts, gen.WithTemplates(os.DirFS(in.Templates)))
+	}
+	if in.Unexported {
+		opts = append(opts, gen.WithUnexportedTypes())
+	}
+	if in.Unknown {
+		opts = append(opts, gen.WithUnknownFields())
+	}
+	if in.Strict {
+		opts = append(opts, gen.WithStrict())
+	}
+	g.opts = append(opts, gen.WithPackage(g.pkg), gen.WithFileName(g.file))
+	return g, nil
+}
+
+// findConfig returns the default configuration file of the working
+// directory, if any.
+func findConfig() string {
+	for _, file := range defaultConfigs {
+		if _, err := os.Stat(file); err == nil {
+			return file
+		}
+	}
+	return ""
+}
@@ -8,6 +8,9 @@ Gowsdl generates Go code from a WSDL file.
 This project is originally intended to generate Go clients for WS-* services.
 
 Usage: gowsdl [options] myservice.wsdl
+       gowsdl [-config gowsdl.yaml]
+       gowsdl bundle [options] myservice.wsdl dir
+       gowsdl cache [options] list|purge [url...]
   -o string
         File where the generated code will be saved (default "myservice.go")
   -p string
@@ -26,34 +29,37 @@ Resolves external XML Schemas
 
 Supports providing WSDL HTTP URL as well as a local WSDL file.
 
+Reads the WSDLs to generate code from, and how, from a gowsdl.yaml or gowsdl.json configuration file.
+
+Splits large generated code into files for the types, the client, the server and the helpers.
+
+Generates code in other programming languages through plugins, which get a language neutral model of the WSDL.
+
 Not supported
 
 UDDI.
 
 TODO
 
-Add support for filters to allow the user to change the generated code.
-
 If WSDL file is local, resolve external XML schemas locally too instead of failing due to not having a URL to download them from.
 
 Resolve XSD element references.
 
 Support for generating namespaces.
 
-Make code generation agnostic so generating code to other programming languages is feasible through plugins.
-
 */
 
 package main
 
 import (
-	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
-	"go/format"
+	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
 	gen "github.com/hooklift/gowsdl"
 )
@@ -70,16 +76,215 @@ var outFile = flag.String("o", "myservice.go", "File where the generated code wi
 var dir = flag.String("d", "./", "Directory under which package directory will be created")
 var insecure = flag.Bool("i", false, "Skips TLS Verification")
 var makePublic = flag.Bool("make-public", true, "Make the generated types public/exported")
+var naming = flag.String("naming", "xsd", "Naming style of generated identifiers: xsd keeps the WSDL names, go uses CamelCase and Go initialisms")
+var initialisms = flag.String("initialisms", "", "Comma separated list of additional initialisms kept in upper case by the go naming style")
+var inlineTypes = flag.String("inline-types", "anonymous", "How to generate complex types declared inline: anonymous structs, or named types joined by underscore (Parent_Child) or concat (ParentChild)")
+var optionalPointers = flag.Bool("optional-pointers", false, "Generate pointers for optional elements and attributes of simple types")
+var requiredValues = flag.Bool("required-values", false, "Generate values instead of pointers for required elements of named types")
+var catalogs = flag.String("catalog", "", "Comma separated list of OASIS XML Catalog files used to find imported schemas locally")
+var schemaGraph = flag.Bool("schema-graph", false, "Print the graph of the imported and included schemas")
+var cacheDir = flag.String("cache-dir", gen.DefaultCacheDir, "Directory of the cache of downloaded WSDL and XSD documents")
+var noCache = flag.Bool("no-cache", false, "Download WSDL and XSD documents without caching them")
+var cacheTTL = flag.Duration("cache-ttl", 0, "How long cached documents are used without checking whether they changed")
+var offline = flag.Bool("offline", false, "Load remote WSDL and XSD documents from the cache only, never downloading them")
+var user = flag.String("user", "", "User and password, as user:password, for basic authentication of downloads")
+var token = flag.String("token", "", "Bearer token sent with downloads")
+var clientCert = flag.String("cert", "", "PEM file of the client certificate presented by downloads")
+var clientKey = flag.String("key", "", "PEM file of the key of the client certificate")
+var caFile = flag.String("ca-file", "", "PEM file of additional certificate authorities trusted by downloads")
+var proxy = flag.String("proxy", "", "Proxy URL for downloads, instead of the one from the environment")
+var headers headerFlags
+var includeOps, excludeOps, credentialOrigins listFlags
+var strict = flag.Bool("strict", false, "Fail on warnings, that is, on any construct that is not supported")
+var filters = flag.String("filter", "", "Comma separated list of built-in filters changing the generated code: validate-tags, yaml-tags, drop-deprecated")
+var templates = flag.String("templates", "", "Directory of templates, named like Elements.tmpl, overriding the templates code is generated with")
+var irFile = flag.String("ir", "", "Write the language neutral model of the WSDL as JSON to this file instead of generating code")
+var plugin = flag.String("plugin", "", "Generate code with this external generator, a path or a name run as gowsdl-gen-name, into the -d directory")
+var pluginOpt = flag.String("plugin-opt", "", "Parameter passed as is to the -plugin generator")
+var configFile = flag.String("config", "", "Configuration file, in YAML or JSON, describing the WSDLs to generate code from, which cannot be combined with a WSDL or other flags (default gowsdl.yaml or gowsdl.json when no WSDL is given)")
+var only = flag.String("only", "", "Generate only the client or the server, along with the types, or only the types: client, server or types")
+var split = flag.Bool("split", false, "Write the types, the client, the server and the enumeration and method helpers to separate files")
+var maxTypesSize = flag.Int("max-types-size", 0, "With -split, shard the types over files of at most about this many bytes")
+var overwrite = flag.String("overwrite", "all", "Which existing files to replace: all, none, or generated ones, failing before writing anything otherwise")
+var unknownFields = flag.Bool("unknown-fields", false, "Capture unknown elements and attributes in generated structs so they survive a round trip")
+
+// headerFlags collects the values of the repeatable -header flag.
+type headerFlags []string
+
+func (h *headerFlags) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *headerFlags) Set(value string) error {
+	if !strings.Contains(value, ":") {
+		return fmt.Errorf("header %q is not in the Name: value form", value)
+	}
+	*h = append(*h, value)
+	return nil
+}
+
+// listFlags collects the values of a repeatable flag.
+type listFlags []string
+
+func (l *listFlags) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *listFlags) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
 
 func init() {
+	flag.Var(&headers, "header", "Additional `Name: value` header sent with downloads, can be repeated")
+	flag.Var(&credentialOrigins, "credentials-origin", "`Origin`, such as https://schemas.example.com, credentials and headers are sent to besides the one of the WSDL, can be repeated")
+	flag.Var(&includeOps, "operation", "Generate only the operations matching this `pattern`, a glob or a /regexp/ matching Operation or PortType.Operation, and the types they need, can be repeated")
+	flag.Var(&excludeOps, "exclude-operation", "Do not generate the operations matching this `pattern`, like -operation, can be repeated")
+
 	log.SetFlags(0)
 	log.SetOutput(os.Stdout)
 	log.SetPrefix("🍀  ")
 }
 
+// flagConfig returns the configuration given by the command line flags, to
+// generate code from wsdlPath.
+func flagConfig(wsdlPath string) *config {
+	c := &config{
+		Download: downloadConfig{
+			User:     *user,
+			Token:    *token,
+			Headers:  headers,
+			Cert:     *clientCert,
+			Key:      *clientKey,
+			CAFile:   *caFile,
+			Proxy:    *proxy,
+			Insecure: *insecure,
+
+			CredentialOrigins: credentialOrigins,
+		},
+		Cache: cacheConfig{
+			Dir:      *cacheDir,
+			Offline:  *offline,
+			Disabled: *noCache,
+		},
+	}
+	if *cacheTTL != 0 {
+		c.Cache.TTL = cacheTTL.String()
+	}
+	if *catalogs != "" {
+		c.Catalogs = strings.Split(*catalogs, ",")
+	}
+
+	in := inputConfig{
+		WSDL:    wsdlPath,
+		Dir:     *dir,
+		Package: *pkg,
+		File:    *outFile,
+		Naming: namingConfig{
+			Style:       *naming,
+			InlineTypes: *inlineTypes,
+		},
+		Occurrences: occurrencesConfig{
+			OptionalPointers: *optionalPointers,
+			RequiredValues:   *requiredValues,
+		},
+		Operations: operationsConfig{
+			Include: includeOps,
+			Exclude: excludeOps,
+		},
+		Split:       *split,
+		MaxTypes:    *maxTypesSize,
+		Overwrite:   *overwrite,
+		Templates:   *templates,
+		Unexported:  !*makePublic,
+		Unknown:     *unknownFields,
+		Strict:      *strict,
+		IR:          *irFile,
+		Plugin:      *plugin,
+		PluginOpt:   *pluginOpt,
+		SchemaGraph: *schemaGraph,
+	}
+	no := false
+	switch *only {
+	case "":
+	case "client":
+		in.Server = &no
+	case "server":
+		in.Client = &no
+	case "types":
+		in.Client, in.Server = &no, &no
+	default:
+		log.Fatalf("unknown -only %q, it is client, server or types", *only)
+	}
+	if *initialisms != "" {
+		in.Naming.Initialisms = strings.Split(*initialisms, ",")
+	}
+	if *filters != "" {
+		for _, name := range strings.Split(*filters, ",") {
+			in.Filters = append(in.Filters, strings.TrimSpace(name))
+		}
+	}
+	c.Inputs = []inputConfig{in}
+	return c
+}
+
+// printDiagnostics prints diagnostics grouped by the document they are
+// about, in the order they were found.
+func printDiagnostics(diagnostics []gen.Diagnostic) {
+	var sources []string
+	bySource := make(map[string][]gen.Diagnostic)
+	for _, d := range diagnostics {
+		if _, ok := bySource[d.Source]; !ok {
+			sources = append(sources, d.Source)
+		}
+		bySource[d.Source] = append(bySource[d.Source], d)
+	}
+
+	for _, source := range sources {
+		if source == "" {
+			log.Println("Generator:")
+		} else {
+			log.Printf("%s:", source)
+		}
+		for _, d := range bySource[source] {
+			if d.Line > 0 {
+				log.Printf("  %d:%d: %s: %s", d.Line, d.Column, d.Severity, d.Message)
+			} else {
+				log.Printf("  %s: %s", d.Severity, d.Message)
+			}
+		}
+	}
+}
+
+// writeModel writes the model of the WSDL as JSON to file.
+func writeModel(gowsdl *gen.GoWSDL, file string) error {
+	model, err := gowsdl.Model()
+	printDiagnostics(gowsdl.Diagnostics())
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(model, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(data, '\n'), 0644)
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "cache" {
+		cacheCommand(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "bundle" {
+		bundleCommand(os.Args[2:])
+		return
+	}
+
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: %s [options] myservice.wsdl\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [-config gowsdl.yaml]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s bundle [options] myservice.wsdl dir\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s cache [options] list|purge [url...]\n", os.Args[0])
 		flag.PrintDefaults()
 	}
 
@@ -91,71 +296,94 @@ func main() {
 		os.Exit(0)
 	}
 
-	if len(os.Args) < 2 {
-		flag.Usage()
-		os.Exit(0)
-	}
-
-	wsdlPath := os.Args[len(os.Args)-1]
-
-	if *outFile == wsdlPath {
-		log.Fatalln("Output file cannot be the same WSDL file")
+	var c *config
+	if *configFile != "" || flag.NArg() == 0 {
+		file := *configFile
+		if file == "" {
+			file = findConfig()
+		}
+		if file == "" {
+			flag.Usage()
+			os.Exit(0)
+		}
+		if err := checkConfigArgs(file); err != nil {
+			log.Fatalln(err)
+		}
+		var err error
+		if c, err = readConfig(file); err != nil {
+			log.Fatalln(err)
+		}
+	} else {
+		c = flagConfig(os.Args[len(os.Args)-1])
 	}
 
-	// load wsdl
-	gowsdl, err := gen.NewGoWSDL(wsdlPath, *pkg, *insecure, *makePublic)
+	gens, err := c.generations()
 	if err != nil {
 		log.Fatalln(err)
 	}
-
-	// generate code
-	gocode, err := gowsdl.Start()
-	if err != nil {
-		log.Fatalln(err)
+	for _, g := range gens {
+		if len(gens) > 1 {
+			log.Printf("Generating code from %s", g.wsdl)
+		}
+		if err := g.run(); err != nil {
+			log.Fatalln(err)
+		}
 	}
 
-	pkg := filepath.Join(*dir, *pkg)
-	err = os.Mkdir(pkg, 0744)
+	log.Println("Done 👍")
+}
 
-	file, err := os.Create(filepath.Join(pkg, *outFile))
-	if err != nil {
-		log.Fatalln(err)
+// checkConfigArgs fails when a WSDL or flags are given along with the
+// configuration file, which would otherwise be ignored.
+func checkConfigArgs(file string) error {
+	if flag.NArg() > 0 {
+		return fmt.Errorf("the WSDL %s cannot be given along with the configuration file %s, list it in the file instead", flag.Arg(0), file)
 	}
-	defer file.Close()
-
-	data := new(bytes.Buffer)
-	data.Write(gocode["header"])
-	data.Write(gocode["types"])
-	data.Write(gocode["operations"])
-	data.Write(gocode["soap"])
+	var set []string
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name != "config" {
+			set = append(set, "-"+f.Name)
+		}
+	})
+	if len(set) > 0 {
+		return fmt.Errorf("the flags %s cannot be given along with the configuration file %s, set them in the file instead", strings.Join(set, ", "), file)
+	}
+	return nil
+}
 
-	// go fmt the generated code
-	source, err := format.Source(data.Bytes())
+// run generates the code.
+func (g *generation) run() error {
+	gowsdl, err := gen.New(g.wsdl, g.opts...)
 	if err != nil {
-		file.Write(data.Bytes())
-		log.Fatalln(err)
+		return err
 	}
 
-	file.Write(source)
-
-	// server
-	serverFile, err := os.Create(pkg + "/" + "server" + *outFile)
-	if err != nil {
-		log.Fatalln(err)
+	if g.irFile != "" {
+		return writeModel(gowsdl, g.irFile)
+	}
+	if g.plugin != "" {
+		result, err := gowsdl.RunPlugin(g.plugin, g.pluginOpt)
+		printDiagnostics(gowsdl.Diagnostics())
+		if err != nil {
+			return err
+		}
+		return result.WriteFiles(g.dir, g.overwrite)
 	}
-	defer serverFile.Close()
 
-	serverData := new(bytes.Buffer)
-	serverData.Write(gocode["server_header"])
-	serverData.Write(gocode["server_wsdl"])
-	serverData.Write(gocode["server"])
+	result, err := gowsdl.Generate()
+	printDiagnostics(gowsdl.Diagnostics())
+	if result == nil {
+		return err
+	}
 
-	serverSource, err := format.Source(serverData.Bytes())
-	if err != nil {
-		serverFile.Write(serverData.Bytes())
-		log.Fatalln(err)
+	if g.schemaGraph {
+		fmt.Print(gowsdl.SchemaGraph())
 	}
-	serverFile.Write(serverSource)
 
-	log.Println("Done 👍")
+	// Code that fails to format is written too, to find out what is wrong
+	// with it.
+	if werr := result.WriteFiles(filepath.Join(g.dir, g.pkg), g.overwrite); werr != nil {
+		return werr
+	}
+	return err
 }
@@ -0,0 +1,108 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"strings"
+)
+
+// valueEdge records that the Go type named from holds a value of the Go type
+// named to, through the field generated for el. Edges without an element
+// come from type definitions, like an element declared with a named type,
+// and cannot be given a pointer.
+type valueEdge struct {
+	from, to string
+	el       *XSDElement
+	at       construct
+}
+
+// addValueEdge records a value edge unless goType is a pointer or a slice,
+// which already provide the indirection a recursive type needs.
+func (st *symbolTable) addValueEdge(from, goType string, el *XSDElement) {
+	if from == "" || goType == "" || strings.HasPrefix(goType, "*") || strings.HasPrefix(goType, "[]") {
+		return
+	}
+	if _, ok := st.edges[from]; !ok {
+		st.edgeOrder = append(st.edgeOrder, from)
+	}
+	edge := &valueEdge{from: from, to: goType, el: el}
+	if el != nil {
+		edge.at = st.elementAt(el)
+	}
+	st.edges[from] = append(st.edges[from], edge)
+}
+
+// breakCycles finds the cycles formed by value edges, which would make
+// invalid recursive types, and turns the edge closing each of them into a
+// pointer. Types are visited in declaration order, so the same edges are
+// chosen on every run.
+func (st *symbolTable) breakCycles() {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	var path []*valueEdge
+
+	var visit func(node string)
+	visit = func(node string) {
+		state[node] = visiting
+		for _, edge := range st.edges[node] {
+			if st.indirect[edge.el] {
+				continue
+			}
+			switch state[edge.to] {
+			case unvisited:
+				path = append(path, edge)
+				visit(edge.to)
+				path = path[:len(path)-1]
+			case visiting:
+				cycle := []*valueEdge{edge}
+				for i := len(path) - 1; i >= 0 && edge.to != node; i-- {
+					cycle = append([]*valueEdge{path[i]}, cycle...)
+					if path[i].from == edge.to {
+						break
+					}
+				}
+				st.breakCycle(cycle)
+			}
+		}
+		state[node] = visited
+	}
+
+	for _, node := range st.edgeOrder {
+		if state[node] == unvisited {
+			visit(node)
+		}
+	}
+}
+
+// breakCycle turns the last edge of cycle that can hold a pointer into one,
+// unless an earlier cycle already did so for one of its edges.
+func (st *symbolTable) breakCycle(cycle []*valueEdge) {
+	names := []string{cycle[0].from}
+	for _, edge := range cycle {
+		if st.indirect[edge.el] {
+			return
+		}
+		names = append(names, edge.to)
+	}
+
+	for i := len(cycle) - 1; i >= 0; i-- {
+		edge := cycle[i]
+		if edge.el == nil {
+			continue
+		}
+		st.indirect[edge.el] = true
+		if goType, ok := st.types[edge.el]; ok {
+			st.types[edge.el] = "*" + goType
+		}
+		st.g.warnf(edge.at, "Using a pointer for field %s of %s to break the recursive type %s",
+			st.names[edge.el], edge.from, strings.Join(names, " -> "))
+		return
+	}
+	st.g.warnf(construct{}, "Recursive type %s cannot be broken with a pointer", strings.Join(names, " -> "))
+}
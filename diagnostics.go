@@ -0,0 +1,146 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+)
+
+// Severity tells whether a Diagnostic prevents code generation.
+type Severity int
+
+const (
+	// SeverityWarning is for constructs that are ignored, or only partly
+	// supported. Generation goes on, unless in strict mode.
+	SeverityWarning Severity = iota
+	// SeverityError is for problems that prevent generating code.
+	SeverityError
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// Diagnostic is a problem found while generating code.
+type Diagnostic struct {
+	Severity Severity
+	Message  string
+
+	// Source is the location of the WSDL or XSD document holding the
+	// construct the diagnostic is about. It is empty for diagnostics that
+	// are not about a construct of a document.
+	Source string
+	// Line and Column locate the construct in Source, starting at 1. They
+	// are zero when unknown.
+	Line, Column int
+}
+
+func (d Diagnostic) String() string {
+	pos := d.Source
+	if d.Line > 0 {
+		pos = fmt.Sprintf("%s:%d:%d", pos, d.Line, d.Column)
+	}
+	if pos == "" {
+		return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", pos, d.Severity, d.Message)
+}
+
+// WithStrict fails generation on warnings, that is, on any construct that
+// is not supported, rather than generating code that ignores it.
+func WithStrict() Option {
+	return func(g *GoWSDL) {
+		g.strict = true
+	}
+}
+
+// Diagnostics returns the warnings and errors found by Start, in the order
+// they were found.
+func (g *GoWSDL) Diagnostics() []Diagnostic {
+	return g.diagnostics
+}
+
+// construct identifies the element of a document a diagnostic is about: the
+// first element with the given local name whose attribute attr has value.
+type construct struct {
+	source  string
+	element string
+	attr    string
+	value   string
+}
+
+// at returns the construct of the component, as declared in the document
+// it was read from.
+func (g *GoWSDL) at(component interface{}, element, attr, value string) construct {
+	return construct{source: g.sources[component], element: element, attr: attr, value: value}
+}
+
+// warnf records a warning about c.
+func (g *GoWSDL) warnf(c construct, format string, args ...interface{}) {
+	g.report(SeverityWarning, c, fmt.Sprintf(format, args...))
+}
+
+// errorf records an error about c.
+func (g *GoWSDL) errorf(c construct, format string, args ...interface{}) {
+	g.report(SeverityError, c, fmt.Sprintf(format, args...))
+}
+
+func (g *GoWSDL) report(severity Severity, c construct, message string) {
+	g.diagnosticsMu.Lock()
+	defer g.diagnosticsMu.Unlock()
+
+	d := Diagnostic{Severity: severity, Message: message, Source: c.source}
+	d.Line, d.Column = g.position(c)
+	g.diagnostics = append(g.diagnostics, d)
+}
+
+// diagnosticsError returns the error Start fails with given the diagnostics
+// found, if any.
+func (g *GoWSDL) diagnosticsError() error {
+	var errs, warnings []Diagnostic
+	for _, d := range g.diagnostics {
+		if d.Severity == SeverityError {
+			errs = append(errs, d)
+		} else {
+			warnings = append(warnings, d)
+		}
+	}
+	switch {
+	case len(errs) > 0:
+		return fmt.Errorf("code generation failed with %d error(s), the first being %s", len(errs), errs[0])
+	case g.strict && len(warnings) > 0:
+		return fmt.Errorf("code generation failed with %d warning(s) in strict mode, the first being %s", len(warnings), warnings[0])
+	}
+	return nil
+}
+
+// position returns the line and column of c in its document, or zeros when
+// c cannot be found. Documents are scanned once, on the first diagnostic
+// about them.
+func (g *GoWSDL) position(c construct) (line, column int) {
+	data, ok := g.documents[c.source]
+	if !ok || c.element == "" {
+		return 0, 0
+	}
+
+	elems, ok := g.scanned[c.source]
+	if !ok {
+		elems, _ = scanElements(data, func(int, xml.Name) bool { return true })
+		g.scanned[c.source] = elems
+	}
+	for _, el := range elems {
+		if el.name.Local == c.element && (c.attr == "" || el.attr(c.attr) == c.value) {
+			line = bytes.Count(data[:el.start], []byte("\n")) + 1
+			column = el.start - bytes.LastIndexByte(data[:el.start], '\n')
+			return line, column
+		}
+	}
+	return 0, 0
+}
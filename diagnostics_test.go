@@ -0,0 +1,75 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const diagnosticsWSDL = `<?xml version="1.0" encoding="utf-8"?>
+<wsdl:definitions xmlns:xs="http://www.w3.org/2001/XMLSchema"
+                  xmlns:wsdl="http://schemas.xmlsoap.org/wsdl/"
+                  targetNamespace="urn:root">
+  <wsdl:types>
+    <xs:schema targetNamespace="urn:root">
+      <xs:complexType name="Order">
+        <xs:sequence>
+          <xs:group ref="Missing"/>
+        </xs:sequence>
+      </xs:complexType>
+    </xs:schema>
+  </wsdl:types>
+  <wsdl:message name="Empty"/>
+</wsdl:definitions>`
+
+func TestDiagnostics(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "service.wsdl")
+	if err := ioutil.WriteFile(file, []byte(diagnosticsWSDL), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Diagnostic{
+		{Severity: SeverityWarning, Message: `Group "Missing" not found`, Source: file, Line: 9, Column: 11},
+		{Severity: SeverityWarning, Message: "Empty message doesn't have any parts, ignoring message...", Source: file, Line: 14, Column: 3},
+	}
+
+	g, err := NewGoWSDL(file, "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Start(); err != nil {
+		t.Fatal(err)
+	}
+	diagnostics := g.Diagnostics()
+	if len(diagnostics) != len(want) {
+		t.Fatalf("got diagnostics %v, want %v", diagnostics, want)
+	}
+	for i := range want {
+		if diagnostics[i] != want[i] {
+			t.Errorf("got diagnostic %v, want %v", diagnostics[i], want[i])
+		}
+	}
+	if got := diagnostics[1].String(); got != file+":14:3: warning: Empty message doesn't have any parts, ignoring message..." {
+		t.Errorf("unexpected diagnostic string %q", got)
+	}
+
+	g, err = NewGoWSDL(file, "myservice", false, true, WithStrict())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = g.Start()
+	if err == nil {
+		t.Fatal("expected warnings to fail generation in strict mode")
+	}
+	if !strings.HasSuffix(err.Error(), "the first being "+want[0].String()) {
+		t.Errorf("got error %q, want it to include the first warning", err)
+	}
+	if len(g.Diagnostics()) != len(want) {
+		t.Errorf("got diagnostics %v in strict mode, want %v", g.Diagnostics(), want)
+	}
+}
@@ -0,0 +1,244 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+var timeout = time.Duration(30 * time.Second)
+
+func dialTimeout(network, addr string) (net.Conn, error) {
+	return net.DialTimeout(network, addr, timeout)
+}
+
+// DownloadOptions configures how the WSDL and the schemas it refers to are
+// downloaded. They apply to every document fetched while generating code.
+type DownloadOptions struct {
+	// Username and Password are sent with HTTP basic authentication.
+	Username string
+	Password string
+
+	// BearerToken is sent in the Authorization header.
+	BearerToken string
+
+	// Header holds additional request headers.
+	Header http.Header
+
+	// ClientCert and ClientKey are the PEM files of the certificate used
+	// for TLS client authentication.
+	ClientCert string
+	ClientKey  string
+
+	// CAFile is a PEM file of the certificate authorities trusted in
+	// addition to the system ones.
+	CAFile string
+
+	// ProxyURL is the proxy requests go through. By default, the proxy
+	// is taken from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment
+	// variables.
+	ProxyURL string
+
+	// CredentialOrigins are the origins, such as https://example.com or
+	// https://schemas.example.com:8443, the credentials and headers above
+	// are sent to, in addition to the origin of the WSDL. Documents of
+	// other origins, which the WSDL and its schemas may refer to, are
+	// downloaded without them.
+	CredentialOrigins []string
+}
+
+// origin returns the scheme, host and port of u, the port being explicit.
+func origin(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		switch strings.ToLower(u.Scheme) {
+		case "http":
+			port = "80"
+		case "https":
+			port = "443"
+		}
+	}
+	return strings.ToLower(u.Scheme) + "://" + net.JoinHostPort(strings.ToLower(u.Hostname()), port)
+}
+
+// parseOrigin parses an origin of DownloadOptions.CredentialOrigins.
+func parseOrigin(rawOrigin string) (string, error) {
+	u, err := url.Parse(rawOrigin)
+	if err != nil || u.Scheme == "" || u.Host == "" || strings.Trim(u.Path, "/") != "" || u.RawQuery != "" || u.User != nil {
+		return "", fmt.Errorf("credential origin %q is not a scheme and a host, such as https://example.com", rawOrigin)
+	}
+	return origin(u), nil
+}
+
+// newHTTPClient returns the client to download documents with.
+func newHTTPClient(ignoreTLS bool, opts DownloadOptions) (*http.Client, error) {
+	if opts.Username != "" && opts.BearerToken != "" {
+		return nil, errors.New("basic authentication and bearer token cannot be used together")
+	}
+	if (opts.ClientCert == "") != (opts.ClientKey == "") {
+		return nil, errors.New("client certificate and key must be given together")
+	}
+	for _, o := range opts.CredentialOrigins {
+		if _, err := parseOrigin(o); err != nil {
+			return nil, err
+		}
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: ignoreTLS,
+	}
+	if opts.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(opts.ClientCert, opts.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if opts.CAFile != "" {
+		pem, err := ioutil.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading CA file: %v", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in CA file %s", opts.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if opts.ProxyURL != "" {
+		u, err := url.Parse(opts.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("parsing proxy URL: %v", err)
+		}
+		proxy = http.ProxyURL(u)
+	}
+
+	tr := &http.Transport{
+		Proxy:           proxy,
+		TLSClientConfig: tlsConfig,
+		Dial:            dialTimeout,
+	}
+	return &http.Client{Transport: tr}, nil
+}
+
+// sendsCredentials reports whether the credentials and headers of the
+// download options are sent with requests for u: whether u has the origin
+// of the WSDL or one of DownloadOptions.CredentialOrigins.
+func (g *GoWSDL) sendsCredentials(u *url.URL) bool {
+	o := origin(u)
+	if g.loc != nil && g.loc.u != nil && origin(g.loc.u) == o {
+		return true
+	}
+	for _, rawOrigin := range g.download.CredentialOrigins {
+		if trusted, err := parseOrigin(rawOrigin); err == nil && trusted == o {
+			return true
+		}
+	}
+	return false
+}
+
+// httpGet sends a GET request for url with the given header, along with
+// the credentials and headers of the download options when url has an
+// origin they are sent to.
+func (g *GoWSDL) httpGet(url string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if g.sendsCredentials(req.URL) {
+		for name, values := range g.download.Header {
+			req.Header[http.CanonicalHeaderKey(name)] = values
+		}
+		if g.download.Username != "" {
+			req.SetBasicAuth(g.download.Username, g.download.Password)
+		}
+		if g.download.BearerToken != "" {
+			req.Header.Set("Authorization", "Bearer "+g.download.BearerToken)
+		}
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	return g.client.Do(req)
+}
+
+// checkRedirect keeps the credentials and headers of the download options
+// from following redirects to origins they are not sent to.
+func (g *GoWSDL) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if !g.sendsCredentials(req.URL) {
+		req.Header.Del("Authorization")
+		for name := range g.download.Header {
+			req.Header.Del(name)
+		}
+	}
+	return nil
+}
+
+// credentials identifies, by a hash, the credentials and headers of the
+// download options sent with requests for rawURL, so that the cache keeps
+// the documents downloaded with different ones apart. It is empty when none
+// are sent.
+func (g *GoWSDL) credentials(rawURL string) string {
+	opts := g.download
+	if u, err := url.Parse(rawURL); err != nil || !g.sendsCredentials(u) {
+		// The client certificate is presented to every server.
+		opts = DownloadOptions{ClientCert: opts.ClientCert}
+	}
+	if opts.Username == "" && opts.BearerToken == "" && len(opts.Header) == 0 && opts.ClientCert == "" {
+		return ""
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%q %q %q %q\n", opts.Username, opts.Password, opts.BearerToken, opts.ClientCert)
+	var headers []string
+	for name, values := range opts.Header {
+		headers = append(headers, fmt.Sprintf("%q %q\n", http.CanonicalHeaderKey(name), values))
+	}
+	sort.Strings(headers)
+	for _, header := range headers {
+		h.Write([]byte(header))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (g *GoWSDL) downloadFile(url string) ([]byte, error) {
+	resp, err := g.httpGet(url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("Received response code %d", resp.StatusCode)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
@@ -0,0 +1,154 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const downloadWSDL = `<?xml version="1.0" encoding="utf-8"?>
+<wsdl:definitions xmlns:xs="http://www.w3.org/2001/XMLSchema"
+                  xmlns:wsdl="http://schemas.xmlsoap.org/wsdl/"
+                  targetNamespace="urn:root">
+  <wsdl:types>
+    <xs:schema targetNamespace="urn:root">
+      <xs:import namespace="urn:types" schemaLocation="types.xsd"/>
+    </xs:schema>
+  </wsdl:types>
+</wsdl:definitions>`
+
+const downloadXSD = `<?xml version="1.0" encoding="utf-8"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:types">
+  <xs:element name="Imported" type="xs:string"/>
+</xs:schema>`
+
+// downloadHandler serves the WSDL and its imported schema, only to requests
+// authorized by check.
+func downloadHandler(t *testing.T, check func(r *http.Request) bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !check(r) {
+			t.Errorf("unauthorized request for %s: %v", r.URL, r.Header)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/service.wsdl":
+			w.Write([]byte(downloadWSDL))
+		case "/types.xsd":
+			w.Write([]byte(downloadXSD))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+}
+
+func generateDownloaded(t *testing.T, url string, opts DownloadOptions) {
+	t.Helper()
+	g, err := NewGoWSDL(url, "myservice", false, true, WithDownloadOptions(opts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(resp["types"]), "type Imported string") {
+		t.Errorf("imported schema not generated:\n%s", resp["types"])
+	}
+}
+
+func TestDownloadOptions(t *testing.T) {
+	t.Run("basic authentication", func(t *testing.T) {
+		server := httptest.NewServer(downloadHandler(t, func(r *http.Request) bool {
+			user, password, ok := r.BasicAuth()
+			return ok && user == "user" && password == "secret"
+		}))
+		defer server.Close()
+
+		generateDownloaded(t, server.URL+"/service.wsdl", DownloadOptions{Username: "user", Password: "secret"})
+	})
+
+	t.Run("bearer token and headers over TLS", func(t *testing.T) {
+		server := httptest.NewTLSServer(downloadHandler(t, func(r *http.Request) bool {
+			return r.Header.Get("Authorization") == "Bearer token" && r.Header.Get("X-Api-Key") == "key"
+		}))
+		defer server.Close()
+
+		caFile := filepath.Join(t.TempDir(), "ca.pem")
+		ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+		if err := ioutil.WriteFile(caFile, ca, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		generateDownloaded(t, server.URL+"/service.wsdl", DownloadOptions{
+			BearerToken: "token",
+			Header:      http.Header{"X-Api-Key": {"key"}},
+			CAFile:      caFile,
+		})
+	})
+
+	t.Run("proxy", func(t *testing.T) {
+		var proxied []string
+		handler := downloadHandler(t, func(r *http.Request) bool { return true })
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			proxied = append(proxied, r.URL.String())
+			handler.ServeHTTP(w, r)
+		}))
+		defer proxy.Close()
+
+		generateDownloaded(t, "http://wsdl.example.com/service.wsdl", DownloadOptions{ProxyURL: proxy.URL})
+		want := []string{"http://wsdl.example.com/service.wsdl", "http://wsdl.example.com/types.xsd"}
+		if strings.Join(proxied, " ") != strings.Join(want, " ") {
+			t.Errorf("got proxied requests %v, want %v", proxied, want)
+		}
+	})
+
+	t.Run("credentials only sent to the origin of the WSDL", func(t *testing.T) {
+		var schemaAuth []string
+		schemas := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			schemaAuth = append(schemaAuth, r.Header.Get("Authorization")+r.Header.Get("X-Api-Key"))
+			w.Write([]byte(downloadXSD))
+		}))
+		defer schemas.Close()
+		wsdl := strings.Replace(downloadWSDL, `schemaLocation="types.xsd"`, `schemaLocation="`+schemas.URL+`/types.xsd"`, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer token" || r.Header.Get("X-Api-Key") != "key" {
+				t.Errorf("credentials not sent to the origin of the WSDL: %v", r.Header)
+			}
+			w.Write([]byte(wsdl))
+		}))
+		defer server.Close()
+
+		opts := DownloadOptions{BearerToken: "token", Header: http.Header{"X-Api-Key": {"key"}}}
+		generateDownloaded(t, server.URL+"/service.wsdl", opts)
+		if len(schemaAuth) != 1 || schemaAuth[0] != "" {
+			t.Errorf("credentials sent to another origin: %q", schemaAuth)
+		}
+
+		opts.CredentialOrigins = []string{schemas.URL}
+		generateDownloaded(t, server.URL+"/service.wsdl", opts)
+		if len(schemaAuth) != 2 || schemaAuth[1] != "Bearer tokenkey" {
+			t.Errorf("credentials not sent to a credential origin: %q", schemaAuth)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := NewGoWSDL("service.wsdl", "myservice", false, true, WithDownloadOptions(DownloadOptions{Username: "user", BearerToken: "token"})); err == nil {
+			t.Error("expected basic authentication and bearer token to be rejected together")
+		}
+		if _, err := NewGoWSDL("service.wsdl", "myservice", false, true, WithDownloadOptions(DownloadOptions{ClientCert: "cert.pem"})); err == nil {
+			t.Error("expected a client certificate without key to be rejected")
+		}
+		if _, err := NewGoWSDL("service.wsdl", "myservice", false, true, WithDownloadOptions(DownloadOptions{CredentialOrigins: []string{"example.com"}})); err == nil {
+			t.Error("expected a credential origin without scheme to be rejected")
+		}
+	})
+}
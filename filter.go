@@ -0,0 +1,329 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Filter changes the code generated from a WSDL through a Transform. Filters
+// run once the WSDL and its schemas are resolved, before Go names are
+// assigned, in the order they are given to WithFilters.
+type Filter func(t *Transform) error
+
+// WithFilters changes the generated code with filters.
+func WithFilters(filters ...Filter) Option {
+	return func(g *GoWSDL) {
+		g.filters = append(g.filters, filters...)
+	}
+}
+
+// Transform is what filters change the generated code through.
+type Transform struct {
+	g *GoWSDL
+}
+
+// Field is an element or an attribute generated as a struct field.
+type Field struct {
+	// Owner is the XSD name of the complex type or element the struct is
+	// generated for, as in the keys of Naming.Fields.
+	Owner string
+	// Element is nil for attributes.
+	Element *XSDElement
+	// Attribute is nil for elements.
+	Attribute *XSDAttribute
+
+	required bool
+}
+
+// Name returns the XSD name of the field.
+func (f Field) Name() string {
+	if f.Attribute != nil {
+		return f.Attribute.Name
+	}
+	if f.Element.Ref != "" {
+		return removeNS(f.Element.Ref)
+	}
+	return f.Element.Name
+}
+
+// Required tells whether the field always occurs: it is a required
+// attribute, or an element that is neither optional nor part of a choice.
+func (f Field) Required() bool {
+	return f.required
+}
+
+func (f Field) node() interface{} {
+	if f.Attribute != nil {
+		return f.Attribute
+	}
+	return f.Element
+}
+
+// WSDL returns the resolved WSDL. Filters may change it to change the
+// generated code.
+func (t *Transform) WSDL() *WSDL {
+	return t.g.wsdl
+}
+
+// Fields returns the fields of the structs generated for complex types, in
+// the order they are declared.
+func (t *Transform) Fields() []Field {
+	var fields []Field
+	var complexType func(owner string, ct *XSDComplexType)
+	elements := func(owner string, els []*XSDElement, inChoice bool) {
+		for _, el := range els {
+			fields = append(fields, Field{Owner: owner, Element: el, required: !inChoice && !isOptional(el.MinOccurs)})
+			if el.Ref == "" && el.Type == "" && el.SimpleType == nil && el.ComplexType != nil {
+				complexType(el.Name, el.ComplexType)
+			}
+		}
+	}
+	attributes := func(owner string, attrs []*XSDAttribute) {
+		for _, attr := range attrs {
+			fields = append(fields, Field{Owner: owner, Attribute: attr, required: attr.Use == "required"})
+		}
+	}
+	complexType = func(owner string, ct *XSDComplexType) {
+		if ext := ct.ComplexContent.Extension; ext.Base != "" {
+			elements(owner, ext.Sequence, false)
+			elements(owner, ext.Choice, true)
+			elements(owner, ext.SequenceChoice, true)
+			attributes(owner, ext.Attributes)
+		} else if ext := ct.SimpleContent.Extension; ext.Base != "" {
+			attributes(owner, ext.Attributes)
+		} else {
+			elements(owner, ct.Sequence, false)
+			elements(owner, ct.Choice, true)
+			elements(owner, ct.SequenceChoice, true)
+			elements(owner, ct.All, false)
+			attributes(owner, ct.Attributes)
+		}
+	}
+
+	for _, schema := range t.g.wsdl.Types.Schemas {
+		for _, el := range schema.Elements {
+			if el.Type == "" && el.ComplexType != nil {
+				complexType(el.Name, el.ComplexType)
+			}
+		}
+		for _, ct := range schema.ComplexTypes {
+			complexType(ct.Name, ct)
+		}
+	}
+	return fields
+}
+
+// RenameType sets the Go name of the type generated for the simple type,
+// complex type or element named xsdName, like Naming.Types.
+func (t *Transform) RenameType(xsdName, goName string) error {
+	if !token.IsIdentifier(goName) {
+		return fmt.Errorf("type %s: %q is not a valid Go identifier", xsdName, goName)
+	}
+	t.g.naming.Types[xsdName] = goName
+	return nil
+}
+
+// RenameField sets the Go name of a field, like Naming.Fields.
+func (t *Transform) RenameField(f Field, goName string) error {
+	if !token.IsIdentifier(goName) {
+		return fmt.Errorf("field %s.%s: %q is not a valid Go identifier", f.Owner, f.Name(), goName)
+	}
+	t.g.naming.Fields[f.Owner+"."+f.Name()] = goName
+	return nil
+}
+
+// SetFieldType sets the Go type of a field, such as "decimal.Decimal" or
+// "[]byte", in place of the one derived from its XSD type. Packages the type
+// refers to are not imported, unless a type of theirs is mapped by WithTypes.
+func (t *Transform) SetFieldType(f Field, goType string) error {
+	if el := f.Element; el != nil && el.Ref == "" && el.Type == "" && el.SimpleType == nil && el.ComplexType != nil {
+		return fmt.Errorf("field %s.%s: its type is declared inline, it cannot be changed", f.Owner, f.Name())
+	}
+	if _, err := parser.ParseExpr(goType); err != nil {
+		return fmt.Errorf("field %s.%s: %q is not a Go type: %v", f.Owner, f.Name(), goType, err)
+	}
+	t.g.fieldTypes[f.node()] = goType
+	return nil
+}
+
+// AddTag adds a key:"value" pair to the struct tag of a field.
+func (t *Transform) AddTag(f Field, key, value string) error {
+	switch {
+	case key == "xml" || key == "json":
+		return fmt.Errorf("field %s.%s: the %s tag is generated, it cannot be added", f.Owner, f.Name(), key)
+	case key == "" || strings.ContainsAny(key, " :\"`") || strings.Contains(value, "`"):
+		return fmt.Errorf("field %s.%s: invalid tag %s:%q", f.Owner, f.Name(), key, value)
+	}
+	for _, tag := range t.g.fieldTags[f.node()] {
+		if tag.key == key {
+			return fmt.Errorf("field %s.%s: tag %s added twice", f.Owner, f.Name(), key)
+		}
+	}
+	t.g.fieldTags[f.node()] = append(t.g.fieldTags[f.node()], fieldTag{key, value})
+	return nil
+}
+
+// DropOperation removes an operation from a port type and its bindings, so
+// that no method is generated for it. It reports whether the operation was
+// found.
+func (t *Transform) DropOperation(portType, operation string) bool {
+	drop := func(ops []*WSDLOperation) ([]*WSDLOperation, bool) {
+		kept := ops[:0]
+		for _, op := range ops {
+			if op.Name != operation {
+				kept = append(kept, op)
+			}
+		}
+		return kept, len(kept) < len(ops)
+	}
+
+	found := false
+	for _, pt := range t.g.wsdl.PortTypes {
+		if pt.Name == portType {
+			var dropped bool
+			pt.Operations, dropped = drop(pt.Operations)
+			found = found || dropped
+		}
+	}
+	for _, binding := range t.g.wsdl.Binding {
+		if stripns(binding.Type) == portType {
+			binding.Operations, _ = drop(binding.Operations)
+		}
+	}
+	return found
+}
+
+// AddMethod adds a method to the type generated for the simple type,
+// complex type or element named xsdName. method returns the declaration of
+// the method given the Go name of the type, once it is known.
+func (t *Transform) AddMethod(xsdName string, method func(goType string) string) {
+	t.g.methods = append(t.g.methods, typeMethod{xsdName, method})
+}
+
+type fieldTag struct {
+	key, value string
+}
+
+type typeMethod struct {
+	xsdName string
+	method  func(goType string) string
+}
+
+// applyFilters runs the filters of g.
+func (g *GoWSDL) applyFilters() error {
+	if len(g.filters) == 0 {
+		return nil
+	}
+
+	// Filters add name overrides, which must not change the maps of the
+	// Naming given by the user.
+	naming := g.naming
+	naming.Types = make(map[string]string)
+	naming.Fields = make(map[string]string)
+	for k, v := range g.naming.Types {
+		naming.Types[k] = v
+	}
+	for k, v := range g.naming.Fields {
+		naming.Fields[k] = v
+	}
+	g.naming = naming
+
+	t := &Transform{g}
+	for _, filter := range g.filters {
+		if err := filter(t); err != nil {
+			return fmt.Errorf("filter: %v", err)
+		}
+	}
+	return nil
+}
+
+// addedTags returns the struct tags added to the field of an element or
+// attribute, with a leading space.
+func (g *GoWSDL) addedTags(node interface{}) string {
+	var tags string
+	for _, tag := range g.fieldTags[node] {
+		tags += " " + tag.key + ":" + strconv.Quote(tag.value)
+	}
+	return tags
+}
+
+// renderMethods returns the declarations of the methods added by filters.
+func (g *GoWSDL) renderMethods() []byte {
+	var code []byte
+	for _, m := range g.methods {
+		name, ok := g.symbols.lookup(nil, kindType, m.xsdName)
+		if !ok {
+			name, ok = g.symbols.lookup(nil, kindElement, m.xsdName)
+		}
+		if !ok {
+			g.warnf(construct{}, "Method added to %s, which is not a generated type", m.xsdName)
+			continue
+		}
+		code = append(code, "\n"+m.method(name)+"\n"...)
+	}
+	return code
+}
+
+// builtinFilters are the filters ParseFilter knows about.
+var builtinFilters = map[string]Filter{
+	// validate-tags adds validate:"required" tags, as understood by
+	// github.com/go-playground/validator, to required fields.
+	"validate-tags": func(t *Transform) error {
+		for _, f := range t.Fields() {
+			if f.Required() {
+				if err := t.AddTag(f, "validate", "required"); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	},
+
+	// yaml-tags adds yaml tags mirroring the json ones.
+	"yaml-tags": func(t *Transform) error {
+		for _, f := range t.Fields() {
+			if err := t.AddTag(f, "yaml", f.Name()+",omitempty"); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+
+	// drop-deprecated drops the operations documented as deprecated.
+	"drop-deprecated": func(t *Transform) error {
+		for _, pt := range t.WSDL().PortTypes {
+			var deprecated []string
+			for _, op := range pt.Operations {
+				if strings.Contains(strings.ToLower(op.Doc), "deprecated") {
+					deprecated = append(deprecated, op.Name)
+				}
+			}
+			for _, op := range deprecated {
+				t.DropOperation(pt.Name, op)
+			}
+		}
+		return nil
+	},
+}
+
+// ParseFilter returns the built-in filter with the given name, as used by
+// the gowsdl command: "validate-tags", "yaml-tags" or "drop-deprecated".
+func ParseFilter(name string) (Filter, error) {
+	if filter, ok := builtinFilters[name]; ok {
+		return filter, nil
+	}
+	var names []string
+	for name := range builtinFilters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("unknown filter %q, filters are %s", name, strings.Join(names, ", "))
+}
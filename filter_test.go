@@ -0,0 +1,84 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestFilters(t *testing.T) {
+	validate, err := ParseFilter("validate-tags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	custom := func(tr *Transform) error {
+		if err := tr.RenameType("Order", "PurchaseOrder"); err != nil {
+			return err
+		}
+		for _, f := range tr.Fields() {
+			switch f.Name() {
+			case "status":
+				if err := tr.RenameField(f, "State"); err != nil {
+					return err
+				}
+			case "id":
+				if err := tr.SetFieldType(f, "int64"); err != nil {
+					return err
+				}
+				if err := tr.AddTag(f, "db", "order_id"); err != nil {
+					return err
+				}
+			}
+		}
+		tr.AddMethod("Order", func(goType string) string {
+			return "func (o *" + goType + ") Open() bool { return o.State == \"open\" }"
+		})
+		if !tr.DropOperation("OrdersPortType", "GetOrder") {
+			t.Error("operation GetOrder not found")
+		}
+		return nil
+	}
+
+	g, err := New("orders.wsdl", WithLoader("", MapLoader{"orders.wsdl": []byte(modelWSDL)}), WithFilters(custom, validate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := g.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := result.Files[0].Content
+	for _, want := range []string{
+		"type PurchaseOrder struct",
+		"State *Status `xml:\"status,omitempty\" json:\"status,omitempty\" validate:\"required\"`",
+		"Line []string `xml:\"line,omitempty\" json:\"line,omitempty\"`",
+		"Id int64 `xml:\"urn:orders id,attr,omitempty\" json:\"id,omitempty\" db:\"order_id\" validate:\"required\"`",
+		"func (o *PurchaseOrder) Open() bool",
+	} {
+		if !bytes.Contains(code, []byte(want)) {
+			t.Errorf("generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if bytes.Contains(code, []byte("GetOrder(")) {
+		t.Error("dropped operation GetOrder is generated")
+	}
+
+	invalid := func(tr *Transform) error {
+		return tr.AddTag(tr.Fields()[0], "json", "state")
+	}
+	g, err = New("orders.wsdl", WithLoader("", MapLoader{"orders.wsdl": []byte(modelWSDL)}), WithFilters(invalid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Generate(); err == nil || !strings.Contains(err.Error(), "the json tag is generated") {
+		t.Errorf("got error %v, want one about the json tag", err)
+	}
+
+	if _, err := ParseFilter("nope"); err == nil || !strings.Contains(err.Error(), "drop-deprecated, validate-tags, yaml-tags") {
+		t.Errorf("got error %v, want one listing the filters", err)
+	}
+}
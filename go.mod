@@ -1,5 +1,8 @@
 module github.com/hooklift/gowsdl
 
-go 1.15
+go 1.16
 
-require github.com/stretchr/testify v1.6.1
+require (
+	github.com/stretchr/testify v1.6.1
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -5,197 +5,376 @@
 package gowsdl
 
 import (
-	"bytes"
-	"crypto/tls"
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"io/ioutil"
 	"log"
-	"net"
 	"net/http"
-	"os"
-	"path/filepath"
 	"regexp"
 	"strings"
 	"sync"
 	"text/template"
-	"time"
 	"unicode"
 )
 
-const maxRecursion uint8 = 20
+// maxRecursion limits how deep schemas can be nested through imports and
+// includes, along any branch of the schema graph.
+const maxRecursion = 20
 
 // GoWSDL defines the struct for WSDL generator.
+//
+// Start resolves the WSDL into a model that is only read while the code is
+// rendered, each rendering keeping its own state, so separate GoWSDLs can
+// generate code concurrently. A GoWSDL generates code once.
 type GoWSDL struct {
-	loc                   *Location
-	rawWSDL               []byte
-	pkg                   string
-	ignoreTLS             bool
-	makePublicFn          func(string) string
-	wsdl                  *WSDL
-	resolvedXSDExternals  map[string]bool
-	currentRecursionLevel uint8
-	currentNamespace      string
+	loc            *Location
+	rawWSDL        []byte
+	pkg            string
+	fileName       string
+	ignoreTLS      bool
+	makePublicFn   func(string) string
+	exportAllTypes bool
+	wsdl           *WSDL
+	download       DownloadOptions
+	client         *http.Client
+	cache          *Cache
+	loaders        map[string]Loader
+	reader         io.Reader
+	readOnce       sync.Once
+	readData       []byte
+	readErr        error
+	catalogs       []string
+	catalog        *catalog
+	schemaGraph    *SchemaGraph
+	resolving      map[*SchemaNode]bool
+	unknownFields  bool
+	naming         Naming
+	occurrences    Occurrences
+	symbols        *symbolTable
+	index          *modelIndex
+	templateFS     fs.FS
+	templates      []templateOverride
+	model          *Model
+	filters        []Filter
+	operations     *Operations
+	includeOps     []operationMatcher
+	excludeOps     []operationMatcher
+	fieldTypes     map[interface{}]string
+	types          map[string]GoType
+	typeNames      map[xml.Name]GoType
+	namespaces     map[string]string
+	layout         Layout
+	fieldTags      map[interface{}][]fieldTag
+	methods        []typeMethod
+	strict         bool
+	diagnostics    []Diagnostic
+	diagnosticsMu  sync.Mutex
+	sources        map[interface{}]string
+	documents      map[string][]byte
+	scanned        map[string][]*xmlElement
 }
 
-// Method setNS sets (and returns) the currently active XML namespace.
-func (g *GoWSDL) setNS(ns string) string {
-	g.currentNamespace = ns
-	return ns
+// Option configures optional behavior of the WSDL generator.
+type Option func(*GoWSDL)
+
+// WithUnknownFields adds catch-all fields to every generated struct. Child
+// elements and attributes that are not described by the WSDL are captured
+// while unmarshaling and written back, after the known fields, on marshal.
+// This keeps data added by newer versions of a service from being dropped
+// in read-modify-write round trips.
+func WithUnknownFields() Option {
+	return func(g *GoWSDL) {
+		g.unknownFields = true
+	}
 }
 
-// Method setNS returns the currently active XML namespace.
-func (g *GoWSDL) getNS() string {
-	return g.currentNamespace
+// WithNaming configures how Go identifiers are derived from the names used
+// by the WSDL and its schemas.
+func WithNaming(naming Naming) Option {
+	return func(g *GoWSDL) {
+		g.naming = naming
+	}
 }
 
-var cacheDir = filepath.Join(os.TempDir(), "gowsdl-cache")
+// WithOccurrences configures how minOccurs, maxOccurs and use shape the Go
+// types of generated fields.
+func WithOccurrences(occurrences Occurrences) Option {
+	return func(g *GoWSDL) {
+		g.occurrences = occurrences
+	}
+}
 
-func init() {
-	err := os.MkdirAll(cacheDir, 0700)
-	if err != nil {
-		log.Println("Create cache directory", "error", err)
-		os.Exit(1)
+// WithDownloadOptions configures authentication, headers, TLS and proxy of
+// the downloads of the WSDL and the schemas it refers to.
+func WithDownloadOptions(opts DownloadOptions) Option {
+	return func(g *GoWSDL) {
+		g.download = opts
 	}
 }
 
-var timeout = time.Duration(30 * time.Second)
+// WithCache caches the documents downloaded while generating code in cache.
+func WithCache(cache *Cache) Option {
+	return func(g *GoWSDL) {
+		g.cache = cache
+	}
+}
 
-func dialTimeout(network, addr string) (net.Conn, error) {
-	return net.DialTimeout(network, addr, timeout)
+// WithCatalogs resolves the schemas the WSDL refers to through the OASIS XML
+// Catalog files at the given locations. Catalogs map the namespaces of
+// imports without a schemaLocation, and the system identifiers and URIs of
+// the schemas, to local copies, so that code can be generated offline.
+func WithCatalogs(locations ...string) Option {
+	return func(g *GoWSDL) {
+		g.catalogs = append(g.catalogs, locations...)
+	}
 }
 
-func downloadFile(url string, ignoreTLS bool) ([]byte, error) {
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: ignoreTLS,
-		},
-		Dial: dialTimeout,
+// Method hasUnknownFields reports whether generated structs get catch-all fields.
+func (g *GoWSDL) hasUnknownFields() bool {
+	return g.unknownFields
+}
+
+// WithPackage sets the name of the package of the generated code. It
+// defaults to myservice.
+func WithPackage(name string) Option {
+	return func(g *GoWSDL) {
+		g.pkg = strings.TrimSpace(name)
 	}
-	client := &http.Client{Transport: tr}
+}
 
-	resp, err := client.Get(url)
-	if err != nil {
-		return nil, err
+// WithFileName sets the name of the file of the generated client, the
+// server going to the same name prefixed with server. It defaults to the
+// name of the package with the .go extension.
+func WithFileName(name string) Option {
+	return func(g *GoWSDL) {
+		g.fileName = name
 	}
+}
 
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("Received response code %d", resp.StatusCode)
+// WithIgnoreTLS skips the verification of the certificates of the servers
+// the WSDL and its schemas are downloaded from.
+func WithIgnoreTLS() Option {
+	return func(g *GoWSDL) {
+		g.ignoreTLS = true
 	}
+}
 
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+// WithUnexportedTypes keeps the case of the names of generated types as in
+// the WSDL, rather than exporting them all.
+func WithUnexportedTypes() Option {
+	return func(g *GoWSDL) {
+		g.exportAllTypes = false
 	}
+}
 
-	return data, nil
+// NewGoWSDL initializes WSDL generator. It is New with the options matching
+// its arguments prepended to opts.
+func NewGoWSDL(file, pkg string, ignoreTLS bool, exportAllTypes bool, opts ...Option) (*GoWSDL, error) {
+	base := []Option{WithPackage(pkg)}
+	if ignoreTLS {
+		base = append(base, WithIgnoreTLS())
+	}
+	if !exportAllTypes {
+		base = append(base, WithUnexportedTypes())
+	}
+	return New(file, append(base, opts...)...)
 }
 
-// NewGoWSDL initializes WSDL generator.
-func NewGoWSDL(file, pkg string, ignoreTLS bool, exportAllTypes bool) (*GoWSDL, error) {
-	file = strings.TrimSpace(file)
+// New returns a generator of Go code for the WSDL at location, which is a
+// file path or a URL. Generated types are exported unless configured
+// otherwise by opts.
+func New(location string, opts ...Option) (*GoWSDL, error) {
+	file := strings.TrimSpace(location)
 	if file == "" {
 		return nil, errors.New("WSDL file is required to generate Go proxy")
 	}
 
-	pkg = strings.TrimSpace(pkg)
-	if pkg == "" {
-		pkg = "myservice"
+	g := &GoWSDL{
+		sources:        make(map[interface{}]string),
+		documents:      make(map[string][]byte),
+		scanned:        make(map[string][]*xmlElement),
+		fieldTypes:     make(map[interface{}]string),
+		fieldTags:      make(map[interface{}][]fieldTag),
+		exportAllTypes: true,
 	}
-	makePublicFn := func(id string) string { return id }
-	if exportAllTypes {
-		makePublicFn = makePublic
+	for _, opt := range opts {
+		opt(g)
 	}
 
-	r, err := ParseLocation(file)
-	if err != nil {
+	if g.pkg == "" {
+		g.pkg = "myservice"
+	}
+	if g.fileName == "" {
+		g.fileName = g.pkg + ".go"
+	}
+	g.makePublicFn = func(id string) string { return id }
+	if g.exportAllTypes {
+		g.makePublicFn = makePublic
+	}
+
+	var err error
+	if archive, entry, ok := splitArchive(file); ok {
+		if err = g.withArchive(archive); err != nil {
+			return nil, err
+		}
+		file = entry
+	}
+	if g.loc, err = g.parseLocation(file); err != nil {
 		return nil, err
 	}
 
-	return &GoWSDL{
-		loc:          r,
-		pkg:          pkg,
-		ignoreTLS:    ignoreTLS,
-		makePublicFn: makePublicFn,
-	}, nil
+	if err := g.naming.validate(); err != nil {
+		return nil, err
+	}
+	if err := g.compileOperations(); err != nil {
+		return nil, err
+	}
+	if err := g.checkTypes(); err != nil {
+		return nil, err
+	}
+	if err := g.loadTemplates(); err != nil {
+		return nil, err
+	}
+	if g.client, err = newHTTPClient(g.ignoreTLS, g.download); err != nil {
+		return nil, err
+	}
+	g.client.CheckRedirect = g.checkRedirect
+
+	return g, nil
 }
 
 // Start initiaties the code generation process by starting two goroutines: one
-// to generate types and another one to generate operations.
+// to generate types and another one to generate operations. It fails when
+// code cannot be generated, or in strict mode when there are warnings. The
+// problems found are available from Diagnostics either way.
+//
+// Start returns the pieces of code that make up the generated files, by
+// name. Generate assembles them into files.
 func (g *GoWSDL) Start() (map[string][]byte, error) {
-	gocode := make(map[string][]byte)
-
-	err := g.unmarshal()
-	if err != nil {
+	if err := g.resolve(); err != nil {
 		return nil, err
 	}
 
-	// Process WSDL nodes
-	for _, schema := range g.wsdl.Types.Schemas {
-		newTraverser(schema, g.wsdl.Types.Schemas).traverse()
+	// Assign Go identifiers
+	g.symbols = newSymbolTable(g)
+	if len(g.templates) > 0 {
+		// For overrides, before renderings start.
+		model, err := g.Model()
+		if err != nil {
+			return nil, err
+		}
+		g.model = model
 	}
 
+	var types, operations, server []byte
+	var typesErr, operationsErr, serverErr error
 	var wg sync.WaitGroup
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		var err error
-
-		gocode["types"], err = g.genTypes()
-		if err != nil {
-			log.Println("genTypes", "error", err)
-		}
+		types, typesErr = g.genTypes()
 	}()
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		var err error
-
-		gocode["operations"], err = g.genOperations()
-		if err != nil {
-			log.Println(err)
-		}
+		operations, operationsErr = g.genOperations()
 	}()
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		var err error
-
-		gocode["server"], err = g.genServer()
-		if err != nil {
-			log.Println(err)
-		}
+		server, serverErr = g.genServer()
 	}()
 
 	wg.Wait()
 
-	gocode["header"], err = g.genHeader()
-	if err != nil {
-		log.Println(err)
+	header, headerErr := g.genHeader()
+	serverHeader, serverHeaderErr := g.genServerHeader()
+
+	for _, gen := range []struct {
+		what string
+		err  error
+	}{
+		{"types", typesErr},
+		{"operations", operationsErr},
+		{"server", serverErr},
+		{"header", headerErr},
+		{"server header", serverHeaderErr},
+	} {
+		if gen.err != nil {
+			g.errorf(construct{}, "Generating %s: %v", gen.what, gen.err)
+		}
 	}
-
-	gocode["server_header"], err = g.genServerHeader()
-	if err != nil {
-		log.Println(err)
+	if err := g.diagnosticsError(); err != nil {
+		return nil, err
 	}
 
-	gocode["server_wsdl"] = []byte("var wsdl = `" + string(g.rawWSDL) + "`")
+	return map[string][]byte{
+		"header":        header,
+		"types":         types,
+		"operations":    operations,
+		"server":        server,
+		"server_header": serverHeader,
+		"server_wsdl":   []byte("var wsdl = `" + string(g.rawWSDL) + "`"),
+	}, nil
+}
 
-	return gocode, nil
+// resolve loads the WSDL and the documents it refers to, then resolves the
+// references between their components, once.
+func (g *GoWSDL) resolve() error {
+	if g.index != nil {
+		return nil
+	}
+	if err := g.unmarshal(); err != nil {
+		return err
+	}
+
+	// Process WSDL nodes
+	g.index = newSchemaIndex(g.wsdl.Types.Schemas)
+	for _, schema := range g.wsdl.Types.Schemas {
+		t := newTraverser(schema, g.index)
+		t.warnf = func(element, attr, value, format string, args ...interface{}) {
+			g.warnf(g.at(schema, element, attr, value), format, args...)
+		}
+		t.traverse()
+	}
+	g.resolveTypes()
+	g.checkMessages()
+	g.selectOperations()
+	if err := g.applyFilters(); err != nil {
+		return err
+	}
+	g.pruneTypes()
+	g.dropMappedTypes()
+	g.index.indexDefinitions(g.wsdl)
+	return nil
 }
 
 func (g *GoWSDL) fetchFile(loc *Location) (data []byte, err error) {
+	if data, ok, err := g.load(loc); ok {
+		return data, err
+	}
 	if loc.f != "" {
 		log.Println("Reading", "file", loc.f)
 		data, err = ioutil.ReadFile(loc.f)
 	} else {
 		log.Println("Downloading", "file", loc.u.String())
-		data, err = downloadFile(loc.u.String(), g.ignoreTLS)
+		if g.cache != nil {
+			data, err = g.cache.fetch(loc.u.String(), g.credentials(loc.u.String()), g.httpGet,
+				func(format string, args ...interface{}) {
+					g.warnf(construct{source: loc.canonical()}, format, args...)
+				})
+		} else {
+			data, err = g.downloadFile(loc.u.String())
+		}
+	}
+	if err == nil {
+		g.documents[loc.canonical()] = data
 	}
 	return
 }
@@ -213,82 +392,330 @@ func (g *GoWSDL) unmarshal() error {
 	}
 	g.rawWSDL = data
 
-	for _, schema := range g.wsdl.Types.Schemas {
-		err = g.resolveXSDExternals(schema, g.loc)
+	if len(g.catalogs) > 0 {
+		if g.catalog, err = g.loadCatalogs(g.catalogs); err != nil {
+			return err
+		}
+	}
+
+	g.addSources(g.wsdl, g.loc)
+	locations := make([]*Location, len(g.wsdl.Types.Schemas))
+	for i := range locations {
+		locations[i] = g.loc
+	}
+	imported, err := g.importWSDLs(g.wsdl, g.loc, map[string]bool{g.loc.canonical(): true})
+	if err != nil {
+		return err
+	}
+	locations = append(locations, imported...)
+
+	g.schemaGraph = newSchemaGraph()
+	g.resolving = make(map[*SchemaNode]bool)
+	for i, schema := range g.wsdl.Types.Schemas {
+		node := &SchemaNode{Location: locations[i].canonical(), Namespace: schema.TargetNamespace}
+		g.schemaGraph.Roots = append(g.schemaGraph.Roots, node)
+	}
+	for i, schema := range g.wsdl.Types.Schemas[:len(g.schemaGraph.Roots)] {
+		err = g.resolveXSDExternals(schema, locations[i], g.schemaGraph.Roots[i], 0)
 		if err != nil {
 			return err
 		}
 	}
+	g.warnUnresolvedImports()
 
 	return nil
 }
 
-func (g *GoWSDL) resolveXSDExternals(schema *XSDSchema, loc *Location) error {
-	download := func(base *Location, ref string) error {
-		location, err := base.Parse(ref)
+// importWSDLs merges the definitions of the WSDLs imported by w, found at
+// loc, into the generated WSDL, and so on recursively. It returns the
+// locations of the schemas embedded in the imported WSDLs, in the order
+// they were appended to the schemas of the generated WSDL.
+func (g *GoWSDL) importWSDLs(w *WSDL, loc *Location, seen map[string]bool) ([]*Location, error) {
+	var locations []*Location
+	for _, imp := range w.Imports {
+		if imp.Location == "" {
+			continue
+		}
+		location, err := loc.Parse(imp.Location)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		schemaKey := location.String()
-		if g.resolvedXSDExternals[location.String()] {
-			return nil
+		if seen[location.canonical()] {
+			continue
+		}
+		seen[location.canonical()] = true
+
+		data, err := g.fetchFile(location)
+		if err != nil {
+			return nil, err
 		}
-		if g.resolvedXSDExternals == nil {
-			g.resolvedXSDExternals = make(map[string]bool, maxRecursion)
+		imported := new(WSDL)
+		if err := xml.Unmarshal(data, imported); err != nil {
+			return nil, fmt.Errorf("WSDL %s: %v", location, err)
+		}
+
+		g.addSources(imported, location)
+		g.wsdl.Messages = append(g.wsdl.Messages, imported.Messages...)
+		g.wsdl.PortTypes = append(g.wsdl.PortTypes, imported.PortTypes...)
+		g.wsdl.Binding = append(g.wsdl.Binding, imported.Binding...)
+		g.wsdl.Service = append(g.wsdl.Service, imported.Service...)
+		g.wsdl.Types.Schemas = append(g.wsdl.Types.Schemas, imported.Types.Schemas...)
+		for range imported.Types.Schemas {
+			locations = append(locations, location)
+		}
+
+		nested, err := g.importWSDLs(imported, location, seen)
+		if err != nil {
+			return nil, err
+		}
+		locations = append(locations, nested...)
+	}
+	return locations, nil
+}
+
+// addSources records that the messages, port types and schemas of w were
+// read from loc.
+func (g *GoWSDL) addSources(w *WSDL, loc *Location) {
+	for _, msg := range w.Messages {
+		g.sources[msg] = loc.canonical()
+	}
+	for _, pt := range w.PortTypes {
+		g.sources[pt] = loc.canonical()
+		for _, op := range pt.Operations {
+			g.sources[op] = loc.canonical()
+		}
+	}
+	for _, schema := range w.Types.Schemas {
+		g.sources[schema] = loc.canonical()
+	}
+}
+
+// SchemaGraph returns the graph of the schemas resolved by Start.
+func (g *GoWSDL) SchemaGraph() *SchemaGraph {
+	return g.schemaGraph
+}
+
+// warnUnresolvedImports warns about imports without a schemaLocation whose
+// namespace is not provided by any of the resolved schemas.
+func (g *GoWSDL) warnUnresolvedImports() {
+	namespaces := make(map[string]bool)
+	for _, schema := range g.wsdl.Types.Schemas {
+		namespaces[schema.TargetNamespace] = true
+	}
+
+	warned := make(map[string]bool)
+	for _, node := range g.schemaNodes() {
+		for _, ref := range node.Refs {
+			if ref.Schema != nil || ref.WellKnown || ref.Location != "" || namespaces[ref.Namespace] || warned[ref.Namespace] {
+				continue
+			}
+			warned[ref.Namespace] = true
+			g.warnf(construct{node.Location, "import", "namespace", ref.Namespace},
+				"Don't know where to find XSD for %s", ref.Namespace)
+		}
+	}
+}
+
+// schemaNodes returns the nodes of the schema graph in the order they were
+// loaded: each root, followed by the schemas it refers to, depth first, so
+// that diagnostics about them come out in the same order on every run.
+func (g *GoWSDL) schemaNodes() []*SchemaNode {
+	var nodes []*SchemaNode
+	seen := make(map[*SchemaNode]bool)
+	var walk func(node *SchemaNode)
+	walk = func(node *SchemaNode) {
+		if seen[node] {
+			return
+		}
+		seen[node] = true
+		nodes = append(nodes, node)
+		for _, ref := range node.Refs {
+			if ref.Schema != nil {
+				walk(ref.Schema)
+			}
+		}
+	}
+	for _, root := range g.schemaGraph.Roots {
+		walk(root)
+	}
+	return nodes
+}
+
+// resolveXSDExternals resolves the imports, includes, redefines and
+// overrides of schema, found at loc and depth levels deep, recording them in
+// the schema graph under node.
+func (g *GoWSDL) resolveXSDExternals(schema *XSDSchema, loc *Location, node *SchemaNode, depth int) error {
+	// download fetches the schema at ref, along with its own externals. It
+	// returns the schemas it added, or none if ref was already resolved.
+	// Schemas included without a target namespace adopt the one of schema.
+	download := func(kind, ref, namespace string) ([]*XSDSchema, error) {
+		r := &SchemaRef{Kind: kind, Location: ref, Namespace: namespace}
+		node.Refs = append(node.Refs, r)
+
+		location, err := loc.Parse(ref)
+		if err != nil {
+			return nil, err
+		}
+		if mapped, ok := g.catalog.resolveURI(location.String()); ok {
+			if location, err = g.parseLocation(mapped); err != nil {
+				return nil, err
+			}
+		}
+
+		key := schemaKey(kind, location.canonical(), namespace)
+		if resolved, ok := g.schemaGraph.nodes[key]; ok {
+			r.Schema = resolved
+			r.Cycle = g.resolving[resolved]
+			return nil, nil
+		}
+
+		if depth >= maxRecursion {
+			r.Unresolved = fmt.Sprintf("more than %d levels deep", maxRecursion)
+			g.warnf(construct{node.Location, kind, "schemaLocation", ref},
+				"Not resolving %s %s from %s: more than %d levels deep", kind, ref, node.Location, maxRecursion)
+			return nil, nil
 		}
-		g.resolvedXSDExternals[schemaKey] = true
 
 		var data []byte
 		if data, err = g.fetchFile(location); err != nil {
-			return err
+			return nil, err
 		}
 
 		newschema := new(XSDSchema)
 
 		err = xml.Unmarshal(data, newschema)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		g.sources[newschema] = location.canonical()
 
-		if (len(newschema.Includes) > 0 || len(newschema.Imports) > 0) &&
-			maxRecursion > g.currentRecursionLevel {
-			g.currentRecursionLevel++
+		if kind != "import" {
+			adoptNamespace(newschema, schema)
+		}
 
-			err = g.resolveXSDExternals(newschema, location)
-			if err != nil {
-				return err
-			}
+		r.Schema = &SchemaNode{Location: location.canonical(), Namespace: newschema.TargetNamespace}
+		g.schemaGraph.nodes[key] = r.Schema
+
+		n := len(g.wsdl.Types.Schemas)
+		g.resolving[r.Schema] = true
+		err = g.resolveXSDExternals(newschema, location, r.Schema, depth+1)
+		delete(g.resolving, r.Schema)
+		if err != nil {
+			return nil, err
 		}
 
 		g.wsdl.Types.Schemas = append(g.wsdl.Types.Schemas, newschema)
 
-		return nil
+		return append([]*XSDSchema{newschema}, g.wsdl.Types.Schemas[n:len(g.wsdl.Types.Schemas)-1]...), nil
 	}
 
 	for _, impts := range schema.Imports {
 		// Download the file only if we have a hint in the form of schemaLocation.
 		if impts.SchemaLocation == "" {
-			log.Printf("[WARN] Don't know where to find XSD for %s", impts.Namespace)
+			if root := g.schemaGraph.root(impts.Namespace); root != nil {
+				node.Refs = append(node.Refs, &SchemaRef{Kind: "import", Namespace: impts.Namespace, Schema: root})
+				continue
+			}
+			if location, ok := g.catalog.resolveNamespace(impts.Namespace); ok {
+				if _, e := download("import", location, impts.Namespace); e != nil {
+					return e
+				}
+				continue
+			}
+
+			// Warned about later, unless another schema provides
+			// the namespace.
+			r := &SchemaRef{Kind: "import", Namespace: impts.Namespace, Unresolved: "no schemaLocation"}
+			if wellKnownNamespaces[impts.Namespace] {
+				r.WellKnown, r.Unresolved = true, ""
+			}
+			node.Refs = append(node.Refs, r)
 			continue
 		}
 
-		if e := download(loc, impts.SchemaLocation); e != nil {
+		if _, e := download("import", impts.SchemaLocation, impts.Namespace); e != nil {
 			return e
 		}
 	}
 
 	for _, incl := range schema.Includes {
-		if e := download(loc, incl.SchemaLocation); e != nil {
+		if _, e := download("include", incl.SchemaLocation, schema.TargetNamespace); e != nil {
 			return e
 		}
 	}
 
+	for i, redefines := range [][]*XSDRedefine{schema.Redefines, schema.Overrides} {
+		kind := [...]string{"redefine", "override"}[i]
+		for _, r := range redefines {
+			schemas, e := download(kind, r.SchemaLocation, schema.TargetNamespace)
+			if e != nil {
+				return e
+			}
+			if len(schemas) == 0 {
+				g.warnf(construct{node.Location, kind, "schemaLocation", r.SchemaLocation},
+					"%s is already resolved, its components cannot be redefined", r.SchemaLocation)
+				continue
+			}
+			redefine(schemas, r, kind == "override", func(component, name string) {
+				g.warnf(construct{node.Location, component, "name", name},
+					"Redefined %s %s not found in %s", component, name, r.SchemaLocation)
+			}, func(component, name string) {
+				g.errorf(construct{node.Location, component, "name", name},
+					"%s %s cannot be redefined, only types and groups can", component, name)
+			})
+		}
+	}
+
 	return nil
 }
 
-func (g *GoWSDL) genTypes() ([]byte, error) {
-	funcMap := template.FuncMap{
-		"toGoType":                 toGoType,
+// typesRender is the state of a rendering of the types template, so that
+// renderings do not share anything mutable.
+type typesRender struct {
+	symbols *symbolTable
+	// namespace is the target namespace of the schema being rendered.
+	namespace string
+}
+
+// Method setNS sets (and returns) the currently active XML namespace.
+func (r *typesRender) setNS(ns string) string {
+	r.namespace = ns
+	return ns
+}
+
+// Method getNS returns the currently active XML namespace.
+func (r *typesRender) getNS() string {
+	return r.namespace
+}
+
+// toGoType maps a XSD type reference of the schema being rendered to the Go
+// type to use for it.
+func (r *typesRender) toGoType(xsdType string, nillable bool) string {
+	return r.symbols.resolveType(r.symbols.xmlns[r.namespace], xsdType, nillable)
+}
+
+// toGoRefType maps a XSD element reference of the schema being rendered to
+// the Go type to use for it.
+func (r *typesRender) toGoRefType(ref string, nillable bool) string {
+	return r.symbols.resolveRefType(r.symbols.xmlns[r.namespace], ref, nillable)
+}
+
+// typesFuncs returns the functions of the types templates, with the state
+// of a new rendering.
+func (g *GoWSDL) typesFuncs() template.FuncMap {
+	r := &typesRender{symbols: g.symbols}
+	return template.FuncMap{
+		"toGoType":                 r.toGoType,
+		"toGoRefType":              r.toGoRefType,
+		"typeName":                 g.symbols.typeName,
+		"fieldName":                g.symbols.fieldName,
+		"jsonKey":                  g.symbols.jsonKey,
+		"enumName":                 g.symbols.enumName,
+		"inlineTypeName":           g.symbols.inlineTypeName,
+		"inlineTypes":              g.symbols.inlineTypesOf,
+		"fieldType":                g.symbols.fieldType,
+		"attributeType":            g.symbols.attributeType,
+		"inlineShape":              g.symbols.inlineShape,
 		"stripns":                  stripns,
 		"replaceReservedWords":     replaceReservedWords,
 		"replaceAttrReservedWords": replaceAttrReservedWords,
@@ -300,66 +727,47 @@ func (g *GoWSDL) genTypes() ([]byte, error) {
 		"goString":                 goString,
 		"findNameByType":           g.findNameByType,
 		"removePointerFromType":    removePointerFromType,
-		"setNS":                    g.setNS,
-		"getNS":                    g.getNS,
-	}
-
-	data := new(bytes.Buffer)
-	tmpl := template.Must(template.New("types").Funcs(funcMap).Parse(typesTmpl))
-	err := tmpl.Execute(data, g.wsdl.Types)
-	if err != nil {
-		return nil, err
+		"setNS":                    r.setNS,
+		"getNS":                    r.getNS,
+		"unknownFields":            g.hasUnknownFields,
+		"fieldTags":                g.addedTags,
 	}
-
-	return data.Bytes(), nil
 }
 
-func (g *GoWSDL) genOperations() ([]byte, error) {
-	funcMap := template.FuncMap{
-		"toGoType":             toGoType,
+// operationsFuncs returns the functions of the operations template.
+func (g *GoWSDL) operationsFuncs() template.FuncMap {
+	return template.FuncMap{
+		"toGoType":             g.symbols.toGoType,
 		"stripns":              stripns,
 		"replaceReservedWords": replaceReservedWords,
 		"normalize":            normalize,
 		"makePublic":           g.makePublicFn,
 		"makePrivate":          makePrivate,
+		"portTypeNames":        g.symbols.portTypeNames,
+		"operationName":        g.symbols.operationName,
 		"findType":             g.findType,
 		"findSOAPAction":       g.findSOAPAction,
 		"findServiceAddress":   g.findServiceAddress,
 	}
-
-	data := new(bytes.Buffer)
-	tmpl := template.Must(template.New("operations").Funcs(funcMap).Parse(opsTmpl))
-	err := tmpl.Execute(data, g.wsdl.PortTypes)
-	if err != nil {
-		return nil, err
-	}
-
-	return data.Bytes(), nil
 }
 
-func (g *GoWSDL) genServer() ([]byte, error) {
-	funcMap := template.FuncMap{
-		"toGoType":             toGoType,
+// serverFuncs returns the functions of the server template.
+func (g *GoWSDL) serverFuncs() template.FuncMap {
+	return template.FuncMap{
+		"toGoType":             g.symbols.toGoType,
 		"stripns":              stripns,
 		"replaceReservedWords": replaceReservedWords,
 		"makePublic":           g.makePublicFn,
 		"findType":             g.findType,
 		"findSOAPAction":       g.findSOAPAction,
 		"findServiceAddress":   g.findServiceAddress,
+		"serverOperations":     g.serverOperations,
 	}
-
-	data := new(bytes.Buffer)
-	tmpl := template.Must(template.New("server").Funcs(funcMap).Parse(serverTmpl))
-	err := tmpl.Execute(data, g.wsdl.PortTypes)
-	if err != nil {
-		return nil, err
-	}
-
-	return data.Bytes(), nil
 }
 
-func (g *GoWSDL) genHeader() ([]byte, error) {
-	funcMap := template.FuncMap{
+// headerFuncs returns the functions of the header template.
+func (g *GoWSDL) headerFuncs() template.FuncMap {
+	return template.FuncMap{
 		"toGoType":             toGoType,
 		"stripns":              stripns,
 		"replaceReservedWords": replaceReservedWords,
@@ -367,36 +775,54 @@ func (g *GoWSDL) genHeader() ([]byte, error) {
 		"makePublic":           g.makePublicFn,
 		"findType":             g.findType,
 		"comment":              comment,
+		"imports":              g.typeImports("context", "encoding/xml", "time", "github.com/hooklift/gowsdl/soap"),
+		"importedTypes":        g.importedTypes,
 	}
-
-	data := new(bytes.Buffer)
-	tmpl := template.Must(template.New("header").Funcs(funcMap).Parse(headerTmpl))
-	err := tmpl.Execute(data, g.pkg)
-	if err != nil {
-		return nil, err
-	}
-
-	return data.Bytes(), nil
 }
 
-func (g *GoWSDL) genServerHeader() ([]byte, error) {
-	funcMap := template.FuncMap{
+// serverHeaderFuncs returns the functions of the server_header template.
+func (g *GoWSDL) serverHeaderFuncs() template.FuncMap {
+	return template.FuncMap{
 		"toGoType":             toGoType,
 		"stripns":              stripns,
 		"replaceReservedWords": replaceReservedWords,
 		"makePublic":           g.makePublicFn,
 		"findType":             g.findType,
 		"comment":              comment,
+		"imports":              g.typeImports("fmt", "errors", "reflect", "strings", "encoding/xml", "net/http"),
+		"importedTypes":        g.importedTypes,
 	}
+}
 
-	data := new(bytes.Buffer)
-	tmpl := template.Must(template.New("server_header").Funcs(funcMap).Parse(serverHeaderTmpl))
-	err := tmpl.Execute(data, g.pkg)
+func (g *GoWSDL) genTypes() ([]byte, error) {
+	// The types of namespaces mapped to packages are not generated.
+	types := WSDLType{Doc: g.wsdl.Types.Doc}
+	for _, schema := range g.wsdl.Types.Schemas {
+		if _, imported := g.namespacePackage(schema.TargetNamespace); !imported {
+			types.Schemas = append(types.Schemas, schema)
+		}
+	}
+	code, err := g.render(typesTemplates, types)
 	if err != nil {
 		return nil, err
 	}
+	return append(code, g.renderMethods()...), nil
+}
 
-	return data.Bytes(), nil
+func (g *GoWSDL) genOperations() ([]byte, error) {
+	return g.render(operationsTemplates, g.wsdl.PortTypes)
+}
+
+func (g *GoWSDL) genServer() ([]byte, error) {
+	return g.render(serverTemplates, g.wsdl.PortTypes)
+}
+
+func (g *GoWSDL) genHeader() ([]byte, error) {
+	return g.render(headerTemplates, g.pkg)
+}
+
+func (g *GoWSDL) genServerHeader() ([]byte, error) {
+	return g.render(serverHeaderTemplates, g.pkg)
 }
 
 var reservedWords = map[string]string{
@@ -566,80 +992,44 @@ func removePointerFromType(goType string) string {
 	return regexp.MustCompile("^\\s*\\*").ReplaceAllLiteralString(goType, "")
 }
 
-// Given a message, finds its type.
-//
-// I'm not very proud of this function but
-// it works for now and performance doesn't
-// seem critical at this point
-func (g *GoWSDL) findType(message string) string {
-	message = stripns(message)
-
+// checkMessages warns about the messages without parts, which are ignored.
+func (g *GoWSDL) checkMessages() {
 	for _, msg := range g.wsdl.Messages {
-		if msg.Name != message {
-			continue
-		}
-
-		// Assumes document/literal wrapped WS-I
 		if len(msg.Parts) == 0 {
 			// Message does not have parts. This could be a Port
 			// with HTTP binding or SOAP 1.2 binding, which are not currently
 			// supported.
-			log.Printf("[WARN] %s message doesn't have any parts, ignoring message...", msg.Name)
-			continue
-		}
-
-		part := msg.Parts[0]
-		if part.Type != "" {
-			return stripns(part.Type)
+			g.warnf(g.at(msg, "message", "name", msg.Name), "%s message doesn't have any parts, ignoring message...", msg.Name)
 		}
+	}
+}
 
-		elRef := stripns(part.Element)
+// Given a message, finds the Go type of its first part.
+func (g *GoWSDL) findType(message string) string {
+	return g.symbols.messageType(stripns(message))
+}
 
-		for _, schema := range g.wsdl.Types.Schemas {
-			for _, el := range schema.Elements {
-				if strings.EqualFold(elRef, el.Name) {
-					if el.Type != "" {
-						return stripns(el.Type)
-					}
-					return el.Name
-				}
-			}
-		}
-	}
-	return ""
+// Returns the operations dispatched by the generated server.
+func (g *GoWSDL) serverOperations() []*serverOperation {
+	return g.symbols.server
 }
 
 // Given a type, check if there's an Element with that type, and return its name.
 func (g *GoWSDL) findNameByType(name string) string {
-	return newTraverser(nil, g.wsdl.Types.Schemas).findNameByType(name)
+	if elName := g.index.elementNames[stripns(name)]; elName != "" {
+		return elName
+	}
+	// Type not found, or used by elements with different names
+	return stripns(name)
 }
 
 // TODO(c4milo): Add support for namespaces instead of striping them out
-// TODO(c4milo): improve runtime complexity if performance turns out to be an issue.
 func (g *GoWSDL) findSOAPAction(operation, portType string) string {
-	for _, binding := range g.wsdl.Binding {
-		if strings.ToUpper(stripns(binding.Type)) != strings.ToUpper(portType) {
-			continue
-		}
-
-		for _, soapOp := range binding.Operations {
-			if soapOp.Name == operation {
-				return soapOp.SOAPOperation.SOAPAction
-			}
-		}
-	}
-	return ""
+	return g.index.soapActions[[2]string{strings.ToUpper(portType), operation}]
 }
 
 func (g *GoWSDL) findServiceAddress(name string) string {
-	for _, service := range g.wsdl.Service {
-		for _, port := range service.Ports {
-			if port.Name == name {
-				return port.SOAPAddress.Location
-			}
-		}
-	}
-	return ""
+	return g.index.addresses[name]
 }
 
 // TODO(c4milo): Add namespace support instead of stripping it
@@ -10,12 +10,15 @@ import (
 	"fmt"
 	"go/ast"
 	"go/format"
+	"go/importer"
 	"go/parser"
 	"go/printer"
 	"go/token"
+	"go/types"
 	"io/ioutil"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -396,3 +399,450 @@ func getFuncDeclaration(resp map[string][]byte, name string, recv string) (strin
 	}
 	return buf.String(), nil
 }
+
+func TestUnknownFields(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/test.wsdl", "myservice", false, true, WithUnknownFields())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := getTypeDeclaration(resp, "GetInfo")
+	if err != nil {
+		fmt.Println(string(resp["types"]))
+		t.Fatal(err)
+	}
+
+	expected := `type GetInfo struct {
+	XMLName	xml.Name	` + "`" + `xml:"http://www.mnb.hu/webservices/ GetInfo"` + "`" + `
+
+	Id	string	` + "`" + `xml:"Id,omitempty" json:"Id,omitempty"` + "`" + `
+
+	UnknownElements	[]soap.AnyElement	` + "`" + `xml:",any" json:"-"` + "`" + `
+
+	UnknownAttrs	[]soap.AnyAttr	` + "`" + `xml:",any,attr" json:"-"` + "`" + `
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+}
+
+// typeCheck type-checks the generated client and server code as one package.
+func typeCheck(resp map[string][]byte) error {
+	client := new(bytes.Buffer)
+	client.Write(resp["header"])
+	client.Write(resp["types"])
+	client.Write(resp["operations"])
+
+	server := new(bytes.Buffer)
+	server.Write(resp["server_header"])
+	server.Write(resp["server_wsdl"])
+	server.Write(resp["server"])
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for name, src := range map[string][]byte{"myservice.go": client.Bytes(), "servermyservice.go": server.Bytes()} {
+		f, err := parser.ParseFile(fset, name, src, 0)
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	_, err := conf.Check("myservice", fset, files, nil)
+	return err
+}
+
+func TestNameCollisions(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/collisions.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := typeCheck(resp); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, expected := range map[string]string{
+		"Customer":     "type Customer struct",
+		"CustomerType": "type CustomerType struct",
+		"FaultType":    "type FaultType struct",
+		"AnyTypeType":  "type AnyTypeType struct",
+		"OrderElement": "type OrderElement struct",
+		"ColorRed2":    `const ColorRed2 Color = "Red"`,
+		"ColorRed3":    `const ColorRed3 Color = "red"`,
+	} {
+		actual, err := getTypeDeclaration(resp, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(actual, expected) {
+			t.Errorf("got %s want %s", actual, expected)
+		}
+	}
+
+	actual, err := getTypeDeclaration(resp, "CustomerType")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{
+		"Id\tstring\t`xml:\"id,omitempty\" json:\"id,omitempty\"`",
+		"Id2\tint32",
+		"XMLName2\tstring",
+		"IdAttr\tstring\t`xml:\"http://example.org/collisions/ id,attr,omitempty\" json:\"idAttr,omitempty\"`",
+	} {
+		if !strings.Contains(actual, field) {
+			t.Errorf("field %q is missing in %s", field, actual)
+		}
+	}
+
+	var renames []string
+	for _, d := range g.Diagnostics() {
+		if strings.HasPrefix(d.Message, "Renamed ") {
+			renames = append(renames, d.String())
+		}
+	}
+	for _, expected := range []string{
+		`fixtures/collisions.wsdl:22:9: warning: Renamed attribute "id" to IdAttr: Id is already declared in struct Customer scope by element "id"`,
+		`fixtures/collisions.wsdl:53:7: warning: Renamed element "Order" to OrderElement: Order is already declared in package scope by complexType "Order"`,
+	} {
+		found := false
+		for _, rename := range renames {
+			found = found || strings.HasSuffix(rename, expected)
+		}
+		if !found {
+			t.Errorf("rename %q is not reported in %q", expected, renames)
+		}
+	}
+}
+
+func TestNamedInlineTypes(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/test.wsdl", "myservice", false, true, WithNaming(Naming{InlineTypes: InlineUnderscore}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := getTypeDeclaration(resp, "ResponseStatus")
+	if err != nil {
+		fmt.Println(string(resp["types"]))
+		t.Fatal(err)
+	}
+
+	expected := `type ResponseStatus struct {
+	Status	[]ResponseStatus_Status	` + "`" + `xml:"status,omitempty" json:"status,omitempty"` + "`" + `
+
+	ResponseCode	string	` + "`" + `xml:"http://www.mnb.hu/webservices/ responseCode,attr,omitempty" json:"responseCode,omitempty"` + "`" + `
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+
+	actual, err = getTypeDeclaration(resp, "ResponseStatus_Status")
+	if err != nil {
+		fmt.Println(string(resp["types"]))
+		t.Fatal(err)
+	}
+
+	expected = `type ResponseStatus_Status struct {
+	Value	string	` + "`" + `xml:",chardata" json:"-,"` + "`" + `
+
+	Code	string	` + "`" + `xml:"http://www.mnb.hu/webservices/ code,attr,omitempty" json:"code,omitempty"` + "`" + `
+}`
+	if actual != expected {
+		t.Error("got \n" + actual + " want \n" + expected)
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/occurs.wsdl", "myservice", false, true, WithOccurrences(Occurrences{
+		OptionalPointers: true,
+		RequiredValues:   true,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := getTypeDeclaration(resp, "Customer")
+	if err != nil {
+		fmt.Println(string(resp["types"]))
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"Name\tstring\t",
+		"Nickname\t*string\t",
+		"Address\tAddress\t",
+		"BillingAddress\t*Address\t",
+		"Tags\t[]string\t",
+		"Code\t[]string\t",
+		"Email\t[]string\t",
+		"Phone\t[]string\t",
+		"Id\tint32\t",
+		"Rank\t*int32\t",
+	}
+	for _, field := range expected {
+		if !strings.Contains(actual, "\t"+field) {
+			t.Errorf("expected field %q in \n%s", field, actual)
+		}
+	}
+	if n := strings.Count(actual, "Items\t"); n != 1 {
+		t.Errorf("expected a single Items field for the wildcards, got %d in \n%s", n, actual)
+	}
+
+	// Members of a repeated choice repeat, members of an optional sequence
+	// are optional.
+	for typeName, fields := range map[string][]string{
+		"Readings":  {"R\t[]string\t", "S\t[]int32\t"},
+		"Signature": {"Signer\t*string\t", "SignedAt\t*Address\t"},
+	} {
+		actual, err := getTypeDeclaration(resp, typeName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, field := range fields {
+			if !strings.Contains(actual, "\t"+field) {
+				t.Errorf("expected field %q in \n%s", field, actual)
+			}
+		}
+	}
+
+	// Elements of groups, nested ones included, take the place of the
+	// references to them, since fields are marshaled in order.
+	actual, err = getTypeDeclaration(resp, "Envelope")
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := -1
+	for _, field := range []string{"\tId\t", "\tFrom\t", "\tStampedAt\t", "\tTo\t", "\tBody\t"} {
+		i := strings.Index(actual, field)
+		if i < last {
+			t.Errorf("expected field %q after the previous ones in \n%s", field, actual)
+		}
+		last = i
+	}
+}
+
+func TestRecursiveTypes(t *testing.T) {
+	for _, inline := range []InlineTypeNaming{InlineAnonymous, InlineUnderscore, InlineConcat} {
+		g, err := NewGoWSDL("fixtures/recursive.wsdl", "myservice", false, true,
+			WithOccurrences(Occurrences{RequiredValues: true}),
+			WithNaming(Naming{InlineTypes: inline}))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := g.Start()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := typeCheck(resp); err != nil {
+			fmt.Println(string(resp["types"]))
+			t.Fatalf("inline types %v: %v", inline, err)
+		}
+
+		actual, err := getTypeDeclaration(resp, "TreeNode")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, field := range []string{"Label\tstring", "Parent\t*TreeNode", "Children\t[]*TreeNode"} {
+			if !strings.Contains(actual, field) {
+				t.Errorf("field %q is missing in %s", field, actual)
+			}
+		}
+	}
+}
+
+func TestChameleonIncludeAndRedefine(t *testing.T) {
+	g, err := NewGoWSDL("fixtures/chameleon/service.wsdl", "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := typeCheck(resp); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, fields := range map[string][]string{
+		// included without a namespace
+		"Money": {`xml:"urn:example:chameleon price"`, `Currency	string	` + "`" + `xml:"urn:example:chameleon currency,attr`},
+		// redefined by extension
+		"Product": {"Name\tstring", "Sku\tstring"},
+		// redefined by restriction
+		"Dimensions": {"Width\tfloat64", "Height\tfloat64", "Unit\tstring"},
+		// redefined group
+		"Record": {"CreatedBy\tstring", "CreatedAt\tsoap.XSDDateTime"},
+		// overridden
+		"Supplier": {"Name\tstring", "Email\tstring"},
+	} {
+		actual, err := getTypeDeclaration(resp, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, field := range fields {
+			if !strings.Contains(actual, field) {
+				t.Errorf("%q is missing in %s", field, actual)
+			}
+		}
+		for _, removed := range []string{"Fax", "Depth", "Scale"} {
+			if strings.Contains(actual, removed) {
+				t.Errorf("field %s removed by a redefinition found in %s", removed, actual)
+			}
+		}
+	}
+
+	if _, err := getTypeDeclaration(resp, "StatusRetired"); err == nil {
+		t.Error("enumeration value removed by the redefinition of Status is still generated")
+	}
+}
+
+const redefineElementWSDL = `<?xml version="1.0" encoding="utf-8"?>
+<wsdl:definitions xmlns:xs="http://www.w3.org/2001/XMLSchema"
+                  xmlns:wsdl="http://schemas.xmlsoap.org/wsdl/"
+                  targetNamespace="urn:example:chameleon">
+  <wsdl:types>
+    <xs:schema targetNamespace="urn:example:chameleon">
+      <xs:redefine schemaLocation="base.xsd">
+        <xs:element name="Product" type="xs:string"/>
+      </xs:redefine>
+    </xs:schema>
+  </wsdl:types>
+</wsdl:definitions>`
+
+func TestRedefineElement(t *testing.T) {
+	base, err := ioutil.ReadFile("fixtures/chameleon/base.xsd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := New("service.wsdl", WithLoader("", MapLoader{
+		"service.wsdl": []byte(redefineElementWSDL),
+		"base.xsd":     base,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Generate(); err == nil || !strings.Contains(err.Error(), "element Product cannot be redefined") {
+		t.Errorf("got error %v for an element within xs:redefine", err)
+	}
+}
+
+// vimSchemas are the target namespaces of the schemas vim.wsdl refers to
+// that are not part of the fixtures, by file name.
+var vimSchemas = map[string]string{
+	"core-types.xsd":           "urn:vim25",
+	"query-messagetypes.xsd":   "urn:vim25",
+	"reflect-messagetypes.xsd": "urn:reflect",
+	"vim-messagetypes.xsd":     "urn:vim25",
+}
+
+// fixtureLoader reads the fixtures from disk, serving empty schemas in place
+// of the ones missing for vim.wsdl so that every fixture generates.
+func fixtureLoader() Option {
+	return WithLoader("", LoaderFunc(func(location string) ([]byte, error) {
+		if ns, ok := vimSchemas[path.Base(location)]; ok {
+			return []byte(`<schema xmlns="http://www.w3.org/2001/XMLSchema" targetNamespace="` + ns + `"/>`), nil
+		}
+		return ioutil.ReadFile(location)
+	}))
+}
+
+func TestConcurrentGeneration(t *testing.T) {
+	files, err := filepath.Glob("fixtures/*.wsdl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	generate := func(file string) (map[string][]byte, error) {
+		g, err := NewGoWSDL(file, "myservice", false, true, fixtureLoader())
+		if err != nil {
+			return nil, err
+		}
+		return g.Start()
+	}
+
+	want := make(map[string]map[string][]byte)
+	for _, file := range files {
+		resp, err := generate(file)
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		want[file] = resp
+	}
+
+	type result struct {
+		file string
+		resp map[string][]byte
+		err  error
+	}
+	results := make(chan result)
+	for i := 0; i < 2; i++ {
+		for file := range want {
+			go func(file string) {
+				resp, err := generate(file)
+				results <- result{file, resp, err}
+			}(file)
+		}
+	}
+	for i := 0; i < 2*len(want); i++ {
+		r := <-results
+		if r.err != nil {
+			t.Errorf("%s: %v", r.file, r.err)
+			continue
+		}
+		for kind, code := range want[r.file] {
+			if !bytes.Equal(r.resp[kind], code) {
+				t.Errorf("%s: %s generated concurrently differ from sequential generation", r.file, kind)
+			}
+		}
+	}
+}
+
+func BenchmarkGenerate(b *testing.B) {
+	files, err := filepath.Glob("fixtures/*.wsdl")
+	if err != nil {
+		b.Fatal(err)
+	}
+	files = append(files, "fixtures/epcis/EPCglobal-epcis-query-1_2.wsdl")
+
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	for _, file := range files {
+		b.Run(strings.TrimSuffix(filepath.Base(file), ".wsdl"), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				g, err := NewGoWSDL(file, "myservice", false, true, fixtureLoader())
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := g.Start(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
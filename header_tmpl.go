@@ -15,15 +15,16 @@ import (
 	"time"
 	"github.com/hooklift/gowsdl/soap"
 
-	{{/*range .Imports*/}}
-		{{/*.*/}}
-	{{/*end*/}}
+	{{range imports}}
+		{{printf "%q" .}}
+	{{end}}
 )
 
 // against "unused imports"
 var _ time.Time
 var _ xml.Name
-
+{{range importedTypes}}var _ {{.}}
+{{end}}
 type AnyType struct {
 	InnerXML string ` + "`" + `xml:",innerxml"` + "`" + `
 }
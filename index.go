@@ -0,0 +1,144 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// modelIndex maps the names of the components of the WSDL and of its
+// schemas to them, so that references are resolved by a lookup rather than
+// by scanning every schema. When components share a name, the first one
+// declared wins, like with the scans it replaces.
+type modelIndex struct {
+	// Global components references are resolved against, by qualified
+	// name.
+	groups     map[xml.Name]*XSDGroup
+	attributes map[xml.Name]*XSDAttribute
+
+	// elements holds the global elements by qualified name, and
+	// localElements the qualified name of the first one declared for each
+	// local name, for references whose prefix does not resolve to the
+	// namespace of the element.
+	elements      map[xml.Name]*XSDElement
+	localElements map[string]xml.Name
+	// foldedElements holds the qualified name of the first element
+	// declared for each lower case local name, since message parts have
+	// always been matched with their elements regardless of case.
+	foldedElements map[string]xml.Name
+	// messages holds the messages having parts, by name.
+	messages map[string]*WSDLMessage
+	// soapActions holds the SOAP actions of the operations of bindings,
+	// by upper case port type and operation name.
+	soapActions map[[2]string]string
+	// addresses holds the SOAP addresses of service ports, by port name.
+	addresses map[string]string
+	// elementNames holds, by local name of type, the name of the elements
+	// of that type, or "" when elements of that type have different names.
+	elementNames map[string]string
+}
+
+// newSchemaIndex indexes the global groups and attributes of schemas.
+func newSchemaIndex(schemas []*XSDSchema) *modelIndex {
+	ix := &modelIndex{
+		groups:     make(map[xml.Name]*XSDGroup),
+		attributes: make(map[xml.Name]*XSDAttribute),
+	}
+	for _, schema := range schemas {
+		for _, group := range schema.Groups {
+			name := xml.Name{Space: schema.TargetNamespace, Local: group.Name}
+			if _, ok := ix.groups[name]; !ok {
+				ix.groups[name] = group
+			}
+		}
+		for _, attr := range schema.Attributes {
+			name := xml.Name{Space: schema.TargetNamespace, Local: attr.Name}
+			if _, ok := ix.attributes[name]; !ok {
+				ix.attributes[name] = attr
+			}
+		}
+	}
+	return ix
+}
+
+// element returns the global element the QName of a message part refers
+// to, given the prefixes in scope, and its namespace. Parts whose QName does
+// not resolve fall back to an element with the same local name, then to one
+// whose local name only differs by case.
+func (ix *modelIndex) element(prefixes map[string]string, ref string) (*XSDElement, string) {
+	name := xmlName(prefixes, ref)
+	if el, ok := ix.elements[name]; ok {
+		return el, name.Space
+	}
+	if local, ok := ix.localElements[name.Local]; ok {
+		return ix.elements[local], local.Space
+	}
+	name = ix.foldedElements[strings.ToLower(name.Local)]
+	return ix.elements[name], name.Space
+}
+
+// xmlName resolves a QName, such as tns:Order, with the namespace
+// declarations in scope.
+func xmlName(xmlns map[string]string, name string) xml.Name {
+	q := resolveQName(xmlns, name)
+	return xml.Name{Space: q.Namespace, Local: q.Local}
+}
+
+// indexDefinitions indexes the elements, messages, bindings and services of
+// w, once the references of its schemas are resolved.
+func (ix *modelIndex) indexDefinitions(w *WSDL) {
+	ix.elements = make(map[xml.Name]*XSDElement)
+	ix.localElements = make(map[string]xml.Name)
+	ix.foldedElements = make(map[string]xml.Name)
+	ix.messages = make(map[string]*WSDLMessage)
+	ix.soapActions = make(map[[2]string]string)
+	ix.addresses = make(map[string]string)
+	ix.elementNames = make(map[string]string)
+
+	t := &traverser{tm: findNameByType, elementNames: ix.elementNames}
+	for _, schema := range w.Types.Schemas {
+		for _, el := range schema.Elements {
+			name := xml.Name{Space: schema.TargetNamespace, Local: el.Name}
+			if _, ok := ix.elements[name]; !ok {
+				ix.elements[name] = el
+			}
+			if _, ok := ix.localElements[el.Name]; !ok {
+				ix.localElements[el.Name] = name
+			}
+			if _, ok := ix.foldedElements[strings.ToLower(el.Name)]; !ok {
+				ix.foldedElements[strings.ToLower(el.Name)] = name
+			}
+			t.traverseElement(el)
+		}
+		for _, ct := range schema.ComplexTypes {
+			t.traverseComplexType(ct)
+		}
+	}
+
+	for _, msg := range w.Messages {
+		if _, ok := ix.messages[msg.Name]; !ok && len(msg.Parts) > 0 {
+			ix.messages[msg.Name] = msg
+		}
+	}
+
+	for _, binding := range w.Binding {
+		portType := strings.ToUpper(stripns(binding.Type))
+		for _, op := range binding.Operations {
+			key := [2]string{portType, op.Name}
+			if _, ok := ix.soapActions[key]; !ok {
+				ix.soapActions[key] = op.SOAPOperation.SOAPAction
+			}
+		}
+	}
+
+	for _, service := range w.Service {
+		for _, port := range service.Ports {
+			if _, ok := ix.addresses[port.Name]; !ok {
+				ix.addresses[port.Name] = port.SOAPAddress.Location
+			}
+		}
+	}
+}
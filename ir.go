@@ -0,0 +1,435 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Model is the language neutral description of a WSDL and of its schemas,
+// for generating code in other languages than Go. It is resolved: imported
+// and included documents are merged, model group and attribute references
+// are expanded, and names are qualified by their namespace.
+//
+// Model is encoded to JSON as is, so its JSON form is as stable as its Go
+// form.
+type Model struct {
+	Name            string      `json:"name,omitempty"`
+	TargetNamespace string      `json:"targetNamespace,omitempty"`
+	Doc             string      `json:"doc,omitempty"`
+	Services        []*Service  `json:"services,omitempty"`
+	PortTypes       []*PortType `json:"portTypes,omitempty"`
+	Messages        []*Message  `json:"messages,omitempty"`
+	// Elements holds the global elements of the schemas.
+	Elements []*Element `json:"elements,omitempty"`
+	// Types holds the global complex and simple types of the schemas.
+	Types []*Type `json:"types,omitempty"`
+}
+
+// QName is a name qualified by the namespace it belongs to. Names of the
+// XML Schema built-in types are in the http://www.w3.org/2001/XMLSchema
+// namespace.
+type QName struct {
+	Namespace string `json:"namespace,omitempty"`
+	Local     string `json:"local"`
+}
+
+// Service is a wsdl:service.
+type Service struct {
+	Name  QName   `json:"name"`
+	Doc   string  `json:"doc,omitempty"`
+	Ports []*Port `json:"ports,omitempty"`
+}
+
+// Port is a wsdl:port of a service, with the SOAP binding it uses.
+type Port struct {
+	Name     string `json:"name"`
+	Doc      string `json:"doc,omitempty"`
+	Binding  QName  `json:"binding"`
+	PortType *QName `json:"portType,omitempty"`
+	// Address is the location of the SOAP endpoint.
+	Address string `json:"address,omitempty"`
+	// Style is the SOAP binding style, document or rpc.
+	Style     string `json:"style,omitempty"`
+	Transport string `json:"transport,omitempty"`
+}
+
+// PortType is a wsdl:portType.
+type PortType struct {
+	Name       QName        `json:"name"`
+	Doc        string       `json:"doc,omitempty"`
+	Operations []*Operation `json:"operations,omitempty"`
+}
+
+// Operation is an operation of a port type.
+type Operation struct {
+	Name string `json:"name"`
+	Doc  string `json:"doc,omitempty"`
+	// SOAPAction is the SOAP action of the operation in the first binding
+	// of its port type.
+	SOAPAction string   `json:"soapAction,omitempty"`
+	Input      *QName   `json:"input,omitempty"`
+	Output     *QName   `json:"output,omitempty"`
+	Faults     []*Fault `json:"faults,omitempty"`
+}
+
+// Fault is a fault an operation may return.
+type Fault struct {
+	Name    string `json:"name"`
+	Message QName  `json:"message"`
+}
+
+// Message is a wsdl:message.
+type Message struct {
+	Name  QName   `json:"name"`
+	Doc   string  `json:"doc,omitempty"`
+	Parts []*Part `json:"parts,omitempty"`
+}
+
+// Part is a part of a message, described by either an element or a type.
+type Part struct {
+	Name    string `json:"name"`
+	Element *QName `json:"element,omitempty"`
+	Type    *QName `json:"type,omitempty"`
+}
+
+// Element is a global element, or an element of a complex type.
+type Element struct {
+	// Name is qualified by the target namespace of the schema for global
+	// elements, and for local ones when the schema qualifies them.
+	Name QName  `json:"name"`
+	Doc  string `json:"doc,omitempty"`
+	// Ref is the global element this element refers to, if any.
+	Ref *QName `json:"ref,omitempty"`
+	// Type is the named type of the element. Elements whose type is
+	// declared inline have an Inline type instead.
+	Type   *QName `json:"type,omitempty"`
+	Inline *Type  `json:"inline,omitempty"`
+	// MinOccurs and MaxOccurs are the occurrence constraints of the
+	// element, MaxOccurs being -1 when unbounded.
+	MinOccurs int  `json:"minOccurs"`
+	MaxOccurs int  `json:"maxOccurs"`
+	Nillable  bool `json:"nillable,omitempty"`
+	// Choice tells whether the element is an alternative of a choice.
+	Choice bool `json:"choice,omitempty"`
+}
+
+// Attribute is an attribute of a complex type.
+type Attribute struct {
+	Name     string `json:"name"`
+	Doc      string `json:"doc,omitempty"`
+	Type     *QName `json:"type,omitempty"`
+	Inline   *Type  `json:"inline,omitempty"`
+	Required bool   `json:"required,omitempty"`
+	Fixed    string `json:"fixed,omitempty"`
+}
+
+// Kinds of Type.
+const (
+	KindComplex = "complex"
+	KindSimple  = "simple"
+)
+
+// Type is a complex or simple type, either global or declared inline.
+type Type struct {
+	// Name is nil for types declared inline.
+	Name *QName `json:"name,omitempty"`
+	// Kind is KindComplex or KindSimple.
+	Kind string `json:"kind"`
+	Doc  string `json:"doc,omitempty"`
+	// Base is the type a complex type extends, or the type a simple type
+	// restricts.
+	Base *QName `json:"base,omitempty"`
+
+	// Fields of complex types.
+	Abstract      bool         `json:"abstract,omitempty"`
+	Mixed         bool         `json:"mixed,omitempty"`
+	SimpleContent bool         `json:"simpleContent,omitempty"`
+	Any           bool         `json:"any,omitempty"`
+	Elements      []*Element   `json:"elements,omitempty"`
+	Attributes    []*Attribute `json:"attributes,omitempty"`
+
+	// Fields of simple types.
+	Facets *Facets `json:"facets,omitempty"`
+	// ItemType is the type of the items of a list.
+	ItemType *QName `json:"itemType,omitempty"`
+	// MemberTypes are the types of the members of a union.
+	MemberTypes []QName `json:"memberTypes,omitempty"`
+}
+
+// Facets are the constraining facets of a simple type restriction.
+type Facets struct {
+	Enumeration  []string `json:"enumeration,omitempty"`
+	Pattern      string   `json:"pattern,omitempty"`
+	MinInclusive string   `json:"minInclusive,omitempty"`
+	MaxInclusive string   `json:"maxInclusive,omitempty"`
+	WhiteSpace   string   `json:"whiteSpace,omitempty"`
+	Length       string   `json:"length,omitempty"`
+	MinLength    string   `json:"minLength,omitempty"`
+	MaxLength    string   `json:"maxLength,omitempty"`
+}
+
+func (f *Facets) empty() bool {
+	return len(f.Enumeration) == 0 && f.Pattern == "" &&
+		f.MinInclusive == "" && f.MaxInclusive == "" && f.WhiteSpace == "" &&
+		f.Length == "" && f.MinLength == "" && f.MaxLength == ""
+}
+
+// Model loads and resolves the WSDL, like Start, and returns its language
+// neutral description. It fails only when the WSDL cannot be loaded; the
+// problems found are available from Diagnostics.
+func (g *GoWSDL) Model() (*Model, error) {
+	if err := g.resolve(); err != nil {
+		return nil, err
+	}
+
+	w := g.wsdl
+	m := &Model{Name: w.Name, TargetNamespace: w.TargetNamespace, Doc: doc(w.Doc)}
+	for _, service := range w.Service {
+		s := &Service{Name: g.wsdlName(service.Name), Doc: doc(service.Doc)}
+		for _, port := range service.Ports {
+			p := &Port{
+				Name:    port.Name,
+				Doc:     doc(port.Doc),
+				Binding: g.wsdlQName(port.Binding),
+				Address: port.SOAPAddress.Location,
+			}
+			for _, binding := range w.Binding {
+				if binding.Name == stripns(port.Binding) {
+					portType := g.wsdlQName(binding.Type)
+					p.PortType = &portType
+					p.Style = binding.SOAPBinding.Style
+					p.Transport = binding.SOAPBinding.Transport
+					break
+				}
+			}
+			s.Ports = append(s.Ports, p)
+		}
+		m.Services = append(m.Services, s)
+	}
+
+	for _, portType := range w.PortTypes {
+		pt := &PortType{Name: g.wsdlName(portType.Name), Doc: doc(portType.Doc)}
+		for _, op := range portType.Operations {
+			o := &Operation{
+				Name:       op.Name,
+				Doc:        doc(op.Doc),
+				SOAPAction: g.findSOAPAction(op.Name, portType.Name),
+				Input:      g.wsdlQNameRef(op.Input.Message),
+				Output:     g.wsdlQNameRef(op.Output.Message),
+			}
+			for _, fault := range op.Faults {
+				o.Faults = append(o.Faults, &Fault{Name: fault.Name, Message: g.wsdlQName(fault.Message)})
+			}
+			pt.Operations = append(pt.Operations, o)
+		}
+		m.PortTypes = append(m.PortTypes, pt)
+	}
+
+	for _, msg := range w.Messages {
+		message := &Message{Name: g.wsdlName(msg.Name), Doc: doc(msg.Doc)}
+		for _, part := range msg.Parts {
+			message.Parts = append(message.Parts, &Part{
+				Name:    part.Name,
+				Element: g.wsdlQNameRef(part.Element),
+				Type:    g.wsdlQNameRef(part.Type),
+			})
+		}
+		m.Messages = append(m.Messages, message)
+	}
+
+	for _, schema := range w.Types.Schemas {
+		for _, el := range schema.Elements {
+			m.Elements = append(m.Elements, modelElement(schema, el, true, false))
+		}
+		for _, ct := range schema.ComplexTypes {
+			t := modelComplexType(schema, ct)
+			t.Name = &QName{Namespace: schema.TargetNamespace, Local: ct.Name}
+			m.Types = append(m.Types, t)
+		}
+		for _, st := range schema.SimpleType {
+			t := modelSimpleType(schema, st)
+			t.Name = &QName{Namespace: schema.TargetNamespace, Local: st.Name}
+			m.Types = append(m.Types, t)
+		}
+	}
+	return m, nil
+}
+
+// wsdlName qualifies the name of a component declared by the WSDL.
+func (g *GoWSDL) wsdlName(name string) QName {
+	return QName{Namespace: g.wsdl.TargetNamespace, Local: name}
+}
+
+// wsdlQName resolves a QName of the WSDL.
+func (g *GoWSDL) wsdlQName(name string) QName {
+	return resolveQName(g.wsdl.Xmlns, name)
+}
+
+// wsdlQNameRef resolves a QName of the WSDL, returning nil for no name.
+func (g *GoWSDL) wsdlQNameRef(name string) *QName {
+	if name == "" {
+		return nil
+	}
+	q := g.wsdlQName(name)
+	return &q
+}
+
+// resolveQName resolves name with the namespace declarations in scope.
+// Unprefixed names are in the default namespace.
+func resolveQName(xmlns map[string]string, name string) QName {
+	if i := strings.Index(name, ":"); i >= 0 {
+		prefix := name[:i]
+		if ns, ok := xmlns[prefix]; ok {
+			prefix = ns
+		}
+		return QName{Namespace: prefix, Local: name[i+1:]}
+	}
+	return QName{Namespace: xmlns[""], Local: name}
+}
+
+// schemaQName resolves a QName of schema, returning nil for no name.
+func schemaQName(schema *XSDSchema, name string) *QName {
+	if name == "" {
+		return nil
+	}
+	q := resolveQName(schema.Xmlns, name)
+	return &q
+}
+
+func modelElement(schema *XSDSchema, el *XSDElement, global, choice bool) *Element {
+	e := &Element{
+		Name:      QName{Local: el.Name},
+		Doc:       doc(el.Doc),
+		Type:      schemaQName(schema, el.Type),
+		MinOccurs: occurs(el.MinOccurs),
+		MaxOccurs: occurs(el.MaxOccurs),
+		Nillable:  el.Nillable,
+		Choice:    choice,
+	}
+	if global || schema.ElementFormDefault == "qualified" {
+		e.Name.Namespace = schema.TargetNamespace
+	}
+	if el.Ref != "" {
+		e.Ref = schemaQName(schema, el.Ref)
+		e.Name = *e.Ref
+	}
+	if el.ComplexType != nil {
+		e.Inline = modelComplexType(schema, el.ComplexType)
+	} else if el.SimpleType != nil {
+		e.Inline = modelSimpleType(schema, el.SimpleType)
+	}
+	return e
+}
+
+func modelComplexType(schema *XSDSchema, ct *XSDComplexType) *Type {
+	t := &Type{
+		Kind:     KindComplex,
+		Abstract: ct.Abstract,
+		Mixed:    ct.Mixed,
+		Any:      len(ct.Any) > 0,
+	}
+	elements := func(els []*XSDElement, choice bool) {
+		for _, el := range els {
+			t.Elements = append(t.Elements, modelElement(schema, el, false, choice))
+		}
+	}
+	attributes := func(attrs []*XSDAttribute) {
+		for _, attr := range attrs {
+			t.Attributes = append(t.Attributes, modelAttribute(schema, attr))
+		}
+	}
+
+	if ext := ct.ComplexContent.Extension; ext.Base != "" {
+		t.Base = schemaQName(schema, ext.Base)
+		elements(ext.Sequence, false)
+		elements(ext.Choice, true)
+		elements(ext.SequenceChoice, true)
+		attributes(ext.Attributes)
+	}
+	if ext := ct.SimpleContent.Extension; ext.Base != "" {
+		t.Base = schemaQName(schema, ext.Base)
+		t.SimpleContent = true
+		attributes(ext.Attributes)
+	}
+	elements(ct.Sequence, false)
+	elements(ct.All, false)
+	elements(ct.Choice, true)
+	elements(ct.SequenceChoice, true)
+	attributes(ct.Attributes)
+	return t
+}
+
+func modelAttribute(schema *XSDSchema, attr *XSDAttribute) *Attribute {
+	a := &Attribute{
+		Name:     attr.Name,
+		Doc:      doc(attr.Doc),
+		Type:     schemaQName(schema, attr.Type),
+		Required: attr.Use == "required",
+		Fixed:    attr.Fixed,
+	}
+	if attr.SimpleType != nil {
+		a.Inline = modelSimpleType(schema, attr.SimpleType)
+	}
+	return a
+}
+
+func modelSimpleType(schema *XSDSchema, st *XSDSimpleType) *Type {
+	t := &Type{Kind: KindSimple, Doc: doc(st.Doc)}
+
+	r := st.Restriction
+	t.Base = schemaQName(schema, r.Base)
+	facets := &Facets{
+		Pattern:      r.Pattern.Value,
+		MinInclusive: r.MinInclusive.Value,
+		MaxInclusive: r.MaxInclusive.Value,
+		WhiteSpace:   r.WhiteSpace.Value,
+		Length:       r.Length.Value,
+		MinLength:    r.MinLength.Value,
+		MaxLength:    r.MaxLength.Value,
+	}
+	for _, value := range r.Enumeration {
+		facets.Enumeration = append(facets.Enumeration, value.Value)
+	}
+	if !facets.empty() {
+		t.Facets = facets
+	}
+
+	if st.List.ItemType != "" {
+		t.ItemType = schemaQName(schema, st.List.ItemType)
+	} else if st.List.SimpleType != nil {
+		t.ItemType = schemaQName(schema, st.List.SimpleType.Restriction.Base)
+	}
+
+	for _, member := range strings.Fields(st.Union.MemberTypes) {
+		t.MemberTypes = append(t.MemberTypes, resolveQName(schema.Xmlns, member))
+	}
+	for _, member := range st.Union.SimpleType {
+		if base := schemaQName(schema, member.Restriction.Base); base != nil {
+			t.MemberTypes = append(t.MemberTypes, *base)
+		}
+	}
+	return t
+}
+
+// occurs returns the value of a minOccurs or maxOccurs attribute, -1 for
+// unbounded.
+func occurs(value string) int {
+	if value == "unbounded" {
+		return -1
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		// Both default to 1.
+		return 1
+	}
+	return n
+}
+
+// doc returns documentation without its surrounding white space.
+func doc(s string) string {
+	return strings.TrimSpace(s)
+}
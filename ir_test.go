@@ -0,0 +1,156 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const modelWSDL = `<?xml version="1.0" encoding="UTF-8"?>
+<definitions name="Orders" targetNamespace="urn:orders:wsdl"
+  xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"
+  xmlns:tns="urn:orders:wsdl"
+  xmlns:o="urn:orders">
+  <types>
+    <xs:schema targetNamespace="urn:orders" elementFormDefault="qualified"
+      xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:o="urn:orders">
+      <xs:simpleType name="Status">
+        <xs:annotation><xs:documentation> State of an order. </xs:documentation></xs:annotation>
+        <xs:restriction base="xs:string">
+          <xs:enumeration value="open"/>
+          <xs:enumeration value="closed"/>
+        </xs:restriction>
+      </xs:simpleType>
+      <xs:group name="Lines">
+        <xs:sequence>
+          <xs:element name="line" type="xs:string" maxOccurs="unbounded"/>
+        </xs:sequence>
+      </xs:group>
+      <xs:complexType name="Order">
+        <xs:sequence>
+          <xs:element name="status" type="o:Status"/>
+          <xs:group ref="o:Lines" minOccurs="0"/>
+        </xs:sequence>
+        <xs:attribute name="id" type="xs:int" use="required"/>
+      </xs:complexType>
+      <xs:element name="GetOrder" type="xs:int"/>
+      <xs:element name="GetOrderResponse" type="o:Order"/>
+    </xs:schema>
+  </types>
+  <message name="GetOrderRequest"><part name="body" element="o:GetOrder"/></message>
+  <message name="GetOrderResponse"><part name="body" element="o:GetOrderResponse"/></message>
+  <portType name="OrdersPortType">
+    <operation name="GetOrder">
+      <input message="tns:GetOrderRequest"/>
+      <output message="tns:GetOrderResponse"/>
+    </operation>
+  </portType>
+  <binding name="OrdersBinding" type="tns:OrdersPortType">
+    <soap:binding style="document" transport="http://schemas.xmlsoap.org/soap/http"/>
+    <operation name="GetOrder">
+      <soap:operation soapAction="urn:GetOrder"/>
+      <input><soap:body use="literal"/></input>
+      <output><soap:body use="literal"/></output>
+    </operation>
+  </binding>
+  <service name="Orders">
+    <port name="OrdersPort" binding="tns:OrdersBinding">
+      <soap:address location="http://example.com/orders"/>
+    </port>
+  </service>
+</definitions>`
+
+func TestModel(t *testing.T) {
+	g, err := New("orders.wsdl", WithLoader("", MapLoader{"orders.wsdl": []byte(modelWSDL)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	model, err := g.Model()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	xs := func(local string) *QName {
+		return &QName{Namespace: "http://www.w3.org/2001/XMLSchema", Local: local}
+	}
+	orders := func(local string) *QName {
+		return &QName{Namespace: "urn:orders", Local: local}
+	}
+	wsdl := func(local string) *QName {
+		return &QName{Namespace: "urn:orders:wsdl", Local: local}
+	}
+
+	want := &Model{
+		Name:            "Orders",
+		TargetNamespace: "urn:orders:wsdl",
+		Services: []*Service{{
+			Name: *wsdl("Orders"),
+			Ports: []*Port{{
+				Name:      "OrdersPort",
+				Binding:   *wsdl("OrdersBinding"),
+				PortType:  wsdl("OrdersPortType"),
+				Address:   "http://example.com/orders",
+				Style:     "document",
+				Transport: "http://schemas.xmlsoap.org/soap/http",
+			}},
+		}},
+		PortTypes: []*PortType{{
+			Name: *wsdl("OrdersPortType"),
+			Operations: []*Operation{{
+				Name:       "GetOrder",
+				SOAPAction: "urn:GetOrder",
+				Input:      wsdl("GetOrderRequest"),
+				Output:     wsdl("GetOrderResponse"),
+			}},
+		}},
+		Messages: []*Message{
+			{Name: *wsdl("GetOrderRequest"), Parts: []*Part{{Name: "body", Element: orders("GetOrder")}}},
+			{Name: *wsdl("GetOrderResponse"), Parts: []*Part{{Name: "body", Element: orders("GetOrderResponse")}}},
+		},
+		Elements: []*Element{
+			{Name: *orders("GetOrder"), Type: xs("int"), MinOccurs: 1, MaxOccurs: 1},
+			{Name: *orders("GetOrderResponse"), Type: orders("Order"), MinOccurs: 1, MaxOccurs: 1},
+		},
+		Types: []*Type{
+			{
+				Name: orders("Order"),
+				Kind: KindComplex,
+				Elements: []*Element{
+					{Name: *orders("status"), Type: orders("Status"), MinOccurs: 1, MaxOccurs: 1},
+					// Expanded from the optional reference to the Lines group.
+					{Name: *orders("line"), Type: xs("string"), MinOccurs: 0, MaxOccurs: -1},
+				},
+				Attributes: []*Attribute{{Name: "id", Type: xs("int"), Required: true}},
+			},
+			{
+				Name:   orders("Status"),
+				Kind:   KindSimple,
+				Doc:    "State of an order.",
+				Base:   xs("string"),
+				Facets: &Facets{Enumeration: []string{"open", "closed"}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(model, want) {
+		got, _ := json.MarshalIndent(model, "", "  ")
+		t.Errorf("got model:\n%s", got)
+	}
+
+	// The JSON form round trips.
+	data, err := json.Marshal(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Model
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&decoded, model) {
+		t.Error("model differs once encoded to JSON and decoded")
+	}
+}
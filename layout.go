@@ -0,0 +1,224 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Layout configures the files the generated code is written to.
+type Layout struct {
+	// Split writes the types, the client operations, the server and the
+	// helpers, that is the enumeration constants and the methods of the
+	// types, to separate files named after the file of the client:
+	// myservice_types.go, myservice_client.go, myservice_server.go and
+	// myservice_helpers.go.
+	Split bool
+
+	// MaxTypesSize shards the types, when split, over files of at most
+	// about that many bytes: myservice_types.go, myservice_types_2.go and
+	// so on. Zero keeps them in one file.
+	MaxTypesSize int
+
+	// NoClient leaves the client operations out, and NoServer the server.
+	// Types are always generated, so that both make a types only package.
+	NoClient bool
+	NoServer bool
+}
+
+// WithLayout configures the files the generated code is written to. By
+// default, the types and the client go to one file and the server to
+// another.
+func WithLayout(layout Layout) Option {
+	return func(g *GoWSDL) {
+		g.layout = layout
+	}
+}
+
+// generatedCode is a top level declaration of generated code, along with
+// the comments preceding it.
+type generatedCode struct {
+	source []byte
+	// helper tells whether the declaration is a method or constants.
+	helper bool
+	// packages holds the names of the packages the declaration refers to.
+	packages map[string]bool
+}
+
+// parseDecls parses generated code and returns its declarations, and the
+// paths of the packages it imports by name.
+func (g *GoWSDL) parseDecls(source []byte) ([]generatedCode, map[string]string, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", source, parser.ParseComments)
+	if err != nil {
+		return nil, nil, err
+	}
+	offset := func(pos token.Pos) int {
+		return fset.Position(pos).Offset
+	}
+
+	imports := make(map[string]string)
+	for _, spec := range f.Imports {
+		p, _ := strconv.Unquote(spec.Path.Value)
+		name := g.importName(p)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = p
+	}
+
+	var decls []generatedCode
+	prev := offset(f.Name.End())
+	for _, decl := range f.Decls {
+		start := prev
+		prev = offset(decl.End())
+		code := generatedCode{
+			source:   bytes.TrimSpace(source[start:prev]),
+			packages: make(map[string]bool),
+		}
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				continue
+			}
+			code.helper = d.Tok == token.CONST
+		case *ast.FuncDecl:
+			code.helper = d.Recv != nil
+		}
+		ast.Inspect(decl, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if id, ok := sel.X.(*ast.Ident); ok {
+					code.packages[id.Name] = true
+				}
+			}
+			return true
+		})
+		decls = append(decls, code)
+	}
+	return decls, imports, nil
+}
+
+// goFile assembles the declarations of a generated file, importing the
+// packages they refer to.
+func (g *GoWSDL) goFile(name string, decls []generatedCode, imports map[string]string) File {
+	var used []string
+	for pkg := range imports {
+		for _, decl := range decls {
+			if decl.packages[pkg] {
+				used = append(used, pkg)
+				break
+			}
+		}
+	}
+	sort.Strings(used)
+
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "// Code generated by gowsdl DO NOT EDIT.\n\npackage %s\n\n", g.pkg)
+	if len(used) > 0 {
+		buf.WriteString("import (\n")
+		for _, pkg := range used {
+			if p := imports[pkg]; g.importName(p) == pkg && path.Base(p) == pkg {
+				fmt.Fprintf(buf, "\t%q\n", p)
+			} else {
+				fmt.Fprintf(buf, "\t%s %q\n", pkg, p)
+			}
+		}
+		buf.WriteString(")\n\n")
+	}
+	for _, decl := range decls {
+		buf.Write(decl.source)
+		buf.WriteString("\n\n")
+	}
+	return File{Name: name, Package: g.pkg, Content: buf.Bytes()}
+}
+
+// splitFiles splits the generated code into files according to the layout.
+// It fails when the generated code does not parse.
+func (g *GoWSDL) splitFiles(code map[string][]byte) ([]File, error) {
+	typesCode := append(append([]byte{}, code["header"]...), code["types"]...)
+	types, imports, err := g.parseDecls(typesCode)
+	if err != nil {
+		return nil, fmt.Errorf("parsing types: %v", err)
+	}
+
+	base := strings.TrimSuffix(g.fileName, ".go")
+	var files []File
+	var shard, helpers []generatedCode
+	size := 0
+	flush := func() {
+		name := base + "_types.go"
+		if n := len(files) + 1; n > 1 {
+			name = fmt.Sprintf("%s_types_%d.go", base, n)
+		}
+		files = append(files, g.goFile(name, shard, imports))
+		shard, size = nil, 0
+	}
+	for _, decl := range types {
+		if decl.helper {
+			helpers = append(helpers, decl)
+			continue
+		}
+		if g.layout.MaxTypesSize > 0 && len(shard) > 0 && size+len(decl.source) > g.layout.MaxTypesSize {
+			flush()
+		}
+		shard = append(shard, decl)
+		size += len(decl.source)
+	}
+	flush()
+
+	if len(helpers) > 0 {
+		files = append(files, g.goFile(base+"_helpers.go", helpers, imports))
+	}
+	if !g.layout.NoClient {
+		operations, _, err := g.parseDecls(append([]byte("package "+g.pkg+"\n"), code["operations"]...))
+		if err != nil {
+			return nil, fmt.Errorf("parsing operations: %v", err)
+		}
+		files = append(files, g.goFile(base+"_client.go", operations, imports))
+	}
+	if !g.layout.NoServer {
+		files = append(files, File{
+			Name:    base + "_server.go",
+			Package: g.pkg,
+			Content: concat(code, "server_header", "server_wsdl", "server"),
+		})
+	}
+	return files, nil
+}
+
+// joinedFiles returns the generated code as one file for the types and the
+// client, and one for the server.
+func (g *GoWSDL) joinedFiles(code map[string][]byte) []File {
+	client := []string{"header", "types"}
+	if !g.layout.NoClient {
+		client = append(client, "operations")
+	}
+	files := []File{{Name: g.fileName, Package: g.pkg, Content: concat(code, client...)}}
+	if !g.layout.NoServer {
+		files = append(files, File{
+			Name:    "server" + g.fileName,
+			Package: g.pkg,
+			Content: concat(code, "server_header", "server_wsdl", "server"),
+		})
+	}
+	return files
+}
+
+// concat joins parts of the generated code.
+func concat(code map[string][]byte, parts ...string) []byte {
+	data := new(bytes.Buffer)
+	for _, part := range parts {
+		data.Write(code[part])
+	}
+	return data.Bytes()
+}
@@ -0,0 +1,80 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLayout(t *testing.T) {
+	for _, test := range []struct {
+		layout Layout
+		names  []string
+	}{
+		{Layout{}, []string{"orders.go", "serverorders.go"}},
+		{Layout{NoServer: true}, []string{"orders.go"}},
+		{Layout{Split: true}, []string{"orders_types.go", "orders_helpers.go", "orders_client.go", "orders_server.go"}},
+		{Layout{Split: true, NoClient: true, NoServer: true}, []string{"orders_types.go", "orders_helpers.go"}},
+	} {
+		g, err := New("orders.wsdl", WithLoader("", MapLoader{"orders.wsdl": []byte(modelWSDL)}),
+			WithFileName("orders.go"), WithLayout(test.layout))
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := g.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var names []string
+		for _, f := range result.Files {
+			names = append(names, f.Name)
+			if !f.Formatted {
+				t.Errorf("%+v: %s is not formatted:\n%s", test.layout, f.Name, f.Content)
+			}
+		}
+		if len(names) != len(test.names) {
+			t.Errorf("%+v: got files %q, want %q", test.layout, names, test.names)
+			continue
+		}
+		for i := range names {
+			if names[i] != test.names[i] {
+				t.Errorf("%+v: got files %q, want %q", test.layout, names, test.names)
+				break
+			}
+		}
+
+		if test.layout.Split && !test.layout.NoClient {
+			client := result.Files[2].Content
+			if !bytes.Contains(client, []byte("\t\"context\"\n")) || bytes.Contains(client, []byte("\"time\"")) {
+				t.Errorf("%+v: the client does not import what it uses:\n%s", test.layout, client)
+			}
+			helpers := result.Files[1].Content
+			if !bytes.Contains(helpers, []byte("StatusOpen Status = \"open\"")) {
+				t.Errorf("%+v: the enumeration is not in the helpers:\n%s", test.layout, helpers)
+			}
+		}
+	}
+
+	// Types are sharded one declaration per file, past the size.
+	g, err := New("orders.wsdl", WithLoader("", MapLoader{"orders.wsdl": []byte(modelWSDL)}),
+		WithFileName("orders.go"), WithLayout(Layout{Split: true, MaxTypesSize: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := g.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := result.Files[1].Name; name != "orders_types_2.go" {
+		t.Errorf("second file is %s, want orders_types_2.go", name)
+	}
+	for _, f := range result.Files {
+		if !f.Formatted {
+			t.Errorf("%s is not formatted:\n%s", f.Name, f.Content)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"strings"
+)
+
+// A Loader reads the WSDL and XSD documents the generator is given or
+// refers to, in place of the local filesystem and the network.
+type Loader interface {
+	// Load returns the content of the document at location: a URL, or a
+	// slash-separated path for the loader of paths.
+	Load(location string) ([]byte, error)
+}
+
+// LoaderFunc adapts a function to the Loader interface.
+type LoaderFunc func(location string) ([]byte, error)
+
+// Load calls f(location).
+func (f LoaderFunc) Load(location string) ([]byte, error) {
+	return f(location)
+}
+
+// FSLoader returns a Loader reading paths from fsys, such as an embed.FS.
+// Paths are relative to the root of fsys.
+func FSLoader(fsys fs.FS) Loader {
+	return LoaderFunc(func(location string) ([]byte, error) {
+		return fs.ReadFile(fsys, location)
+	})
+}
+
+// MapLoader is a Loader serving documents held in memory, by location.
+type MapLoader map[string][]byte
+
+// Load returns the document stored for location.
+func (m MapLoader) Load(location string) ([]byte, error) {
+	data, ok := m[location]
+	if !ok {
+		return nil, &fs.PathError{Op: "load", Path: location, Err: os.ErrNotExist}
+	}
+	return data, nil
+}
+
+// WithLoader loads the documents whose location has the given URL scheme
+// through loader, instead of downloading them. Relative references from a
+// document resolve against its URL, so hierarchical URLs such as
+// mem:///dir/service.wsdl should be used.
+//
+// The empty scheme stands for paths: once a loader is set for it, the WSDL
+// file and the schemas it refers to are read through it rather than from
+// the local filesystem. Paths are then slash-separated, resolved relative
+// to each other like in an fs.FS, and given to the loader cleaned and
+// without a leading slash.
+func WithLoader(scheme string, loader Loader) Option {
+	return func(g *GoWSDL) {
+		if g.loaders == nil {
+			g.loaders = make(map[string]Loader)
+		}
+		g.loaders[strings.ToLower(scheme)] = loader
+	}
+}
+
+// WithReader reads the WSDL from r rather than loading it from the file
+// given to NewGoWSDL. That file still locates the WSDL, to resolve the
+// relative references to schemas against. r is read once, the first time
+// the WSDL is needed, and its content is kept for code generations and
+// bundles that follow.
+func WithReader(r io.Reader) Option {
+	return func(g *GoWSDL) {
+		g.reader = r
+	}
+}
+
+// parseLocation parses rawloc like ParseLocation, or as a path of the
+// virtual filesystem when a loader of paths is set.
+func (g *GoWSDL) parseLocation(rawloc string) (*Location, error) {
+	if _, ok := g.loaders[""]; !ok {
+		return ParseLocation(rawloc)
+	}
+	return (&Location{p: "."}).Parse(rawloc)
+}
+
+// load reads the document at loc through the loader registered for it, and
+// reports whether there is one.
+func (g *GoWSDL) load(loc *Location) ([]byte, bool, error) {
+	if loc == g.loc && g.reader != nil {
+		g.readOnce.Do(func() {
+			log.Println("Reading", "file", loc, "from reader")
+			g.readData, g.readErr = ioutil.ReadAll(g.reader)
+		})
+		return g.readData, true, g.readErr
+	}
+
+	scheme := ""
+	if loc.isURL() {
+		scheme = strings.ToLower(loc.u.Scheme)
+	} else if !loc.isPath() {
+		return nil, false, nil
+	}
+
+	loader, ok := g.loaders[scheme]
+	if !ok {
+		return nil, false, nil
+	}
+	log.Println("Loading", "file", loc)
+	data, err := loader.Load(loc.String())
+	if err != nil {
+		return nil, true, fmt.Errorf("loading %s: %w", loc, err)
+	}
+	return data, true, nil
+}
+
+// cleanPath returns the fs.FS form of the slash-separated path p.
+func cleanPath(p string) string {
+	if p = strings.TrimPrefix(path.Clean("/"+p), "/"); p == "" {
+		return "."
+	}
+	return p
+}
@@ -0,0 +1,102 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+const loaderWSDL = `<?xml version="1.0" encoding="utf-8"?>
+<wsdl:definitions xmlns:xs="http://www.w3.org/2001/XMLSchema"
+                  xmlns:wsdl="http://schemas.xmlsoap.org/wsdl/"
+                  targetNamespace="urn:root">
+  <wsdl:types>
+    <xs:schema targetNamespace="urn:root">
+      <xs:import namespace="urn:types" schemaLocation="../common/types.xsd"/>
+    </xs:schema>
+  </wsdl:types>
+</wsdl:definitions>`
+
+func TestLoader(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		opts []Option
+	}{
+		{
+			name: "fs",
+			file: "service/service.wsdl",
+			opts: []Option{WithLoader("", FSLoader(fstest.MapFS{
+				"service/service.wsdl": {Data: []byte(loaderWSDL)},
+				"common/types.xsd":     {Data: []byte(downloadXSD)},
+			}))},
+		},
+		{
+			name: "scheme",
+			file: "mem:///service/service.wsdl",
+			opts: []Option{WithLoader("mem", MapLoader{
+				"mem:///service/service.wsdl": []byte(loaderWSDL),
+				"mem:///common/types.xsd":     []byte(downloadXSD),
+			})},
+		},
+		{
+			name: "reader",
+			file: "/service/service.wsdl",
+			opts: []Option{
+				WithReader(strings.NewReader(loaderWSDL)),
+				WithLoader("", MapLoader{"common/types.xsd": []byte(downloadXSD)}),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g, err := NewGoWSDL(test.file, "myservice", false, true, test.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := g.Start()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(resp["types"]), "type Imported string") {
+				t.Errorf("imported schema not generated:\n%s", resp["types"])
+			}
+		})
+	}
+
+	// The reader is read once, and its content reused by the following
+	// generations, bundles included.
+	g, err := NewGoWSDL("/service/service.wsdl", "myservice", false, true,
+		WithReader(strings.NewReader(loaderWSDL)),
+		WithLoader("", MapLoader{"common/types.xsd": []byte(downloadXSD)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := g.Bundle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(bundle.Files[bundle.Main]), "definitions") {
+		t.Errorf("WSDL not bundled:\n%s", bundle.Files[bundle.Main])
+	}
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(resp["types"]), "type Imported string") {
+		t.Errorf("imported schema not generated after bundling:\n%s", resp["types"])
+	}
+
+	g, err = NewGoWSDL("missing.wsdl", "myservice", false, true, WithLoader("", MapLoader{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Start(); err == nil || !strings.Contains(err.Error(), "missing.wsdl") {
+		t.Errorf("got error %v, want one naming missing.wsdl", err)
+	}
+}
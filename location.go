@@ -6,15 +6,19 @@ package gowsdl
 
 import (
 	"net/url"
+	"path"
 	"path/filepath"
+	"strings"
 )
 
 // A Location encapsulate information about the loc of WSDL/XSD.
 //
-// It could be either URL or an absolute file path.
+// It could be either URL, an absolute file path, or a path within the
+// virtual filesystem of a Loader.
 type Location struct {
 	u *url.URL
 	f string
+	p string
 }
 
 // ParseLocation parses a rawloc into a Location structure.
@@ -46,6 +50,10 @@ func (r *Location) Parse(ref string) (*Location, error) {
 		return &Location{u: u}, nil
 	}
 
+	if r.p != "" {
+		return r.parsePath(ref), nil
+	}
+
 	if filepath.IsAbs(ref) {
 		return &Location{f: ref}, nil
 	}
@@ -56,7 +64,30 @@ func (r *Location) Parse(ref string) (*Location, error) {
 		}
 	}
 
-	return &Location{f: filepath.Join(filepath.Dir(r.f), ref)}, nil
+	f := filepath.Join(filepath.Dir(r.f), ref)
+	if strings.HasSuffix(ref, "/") {
+		// Like URLs, keep denoting a directory, so that references
+		// relative to it are resolved within it.
+		f += string(filepath.Separator)
+	}
+	return &Location{f: f}, nil
+}
+
+// parsePath resolves ref against the virtual filesystem path of r.
+func (r *Location) parsePath(ref string) *Location {
+	if u, err := url.Parse(ref); err == nil && u.Scheme != "" {
+		return &Location{u: u}
+	}
+
+	p := ref
+	if !path.IsAbs(ref) {
+		p = path.Join(path.Dir(r.p), ref)
+	}
+	p = cleanPath(p)
+	if strings.HasSuffix(ref, "/") && p != "." {
+		p += "/"
+	}
+	return &Location{p: p}
 }
 
 // IsFile determines whether the Location contains a file path.
@@ -64,6 +95,11 @@ func (r *Location) isFile() bool {
 	return r.f != ""
 }
 
+// isPath determines whether the Location contains a virtual filesystem path.
+func (r *Location) isPath() bool {
+	return r.p != ""
+}
+
 // IsFile determines whether the Location contains URL.
 func (r *Location) isURL() bool {
 	return r.u != nil
@@ -77,5 +113,35 @@ func (r *Location) String() string {
 	if r.isURL() {
 		return r.u.String()
 	}
-	return ""
+	return r.p
+}
+
+// canonical returns the String form of the Location, normalized so that
+// different spellings of the same location are equal: file paths are
+// cleaned, and URLs get a lower case scheme and host, no default port, a
+// cleaned path and no fragment.
+func (r *Location) canonical() string {
+	if r.isFile() {
+		return filepath.Clean(r.f)
+	}
+	if r.isPath() {
+		return cleanPath(r.p)
+	}
+	if !r.isURL() {
+		return ""
+	}
+
+	u := *r.u
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		// Only the port is dropped, IPv6 addresses keep their brackets.
+		u.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+	if u.Path != "" {
+		u.Path = path.Clean(u.Path)
+		u.RawPath = ""
+	}
+	u.Fragment = ""
+	return u.String()
 }
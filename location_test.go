@@ -145,3 +145,52 @@ func TestLocation_Parse_FileToURL(t *testing.T) {
 		}
 	}
 }
+
+func TestLocation_Canonical(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"HTTP://Example.ORG:80/a/./b/../my.xsd#frag", "http://example.org/a/my.xsd"},
+		{"https://example.org:443/my.xsd?v=1", "https://example.org/my.xsd?v=1"},
+		{"http://example.org:8080/my.xsd", "http://example.org:8080/my.xsd"},
+		{"http://[::1]:80/my.xsd", "http://[::1]/my.xsd"},
+		{"https://[FE80::1]:8443/my.xsd", "https://[fe80::1]:8443/my.xsd"},
+	}
+	for _, test := range tests {
+		r, err := ParseLocation(test.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.canonical() != test.expected {
+			t.Errorf("got %s wanted %s", r.canonical(), test.expected)
+		}
+	}
+
+	r, err := ParseLocation("fixtures/../fixtures/test.wsdl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := filepath.Abs("fixtures/test.wsdl")
+	if r.canonical() != expected {
+		t.Errorf("got %s wanted %s", r.canonical(), expected)
+	}
+}
+
+func TestLocation_Parse_Directory(t *testing.T) {
+	r, err := ParseLocation("/schemas/catalog.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = r.Parse("local/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = r.Parse("a.xsd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := filepath.FromSlash("/schemas/local/a.xsd"); r.String() != expected {
+		t.Errorf("got %s wanted %s", r.String(), expected)
+	}
+}
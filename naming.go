@@ -0,0 +1,998 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"encoding/xml"
+	"fmt"
+	"go/token"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// NamingStyle selects how XSD and WSDL names are turned into Go identifiers.
+type NamingStyle int
+
+const (
+	// NamingXSD keeps names as they are in the XSD or WSDL, only replacing
+	// characters that are not valid in Go identifiers and capitalizing them
+	// when exported. This is the default.
+	NamingXSD NamingStyle = iota
+
+	// NamingGo converts snake, kebab and dot case names to CamelCase and
+	// applies Go initialisms, so customer_id becomes CustomerID.
+	NamingGo
+)
+
+// ParseNamingStyle parses the name of a naming style, as used by the
+// gowsdl command: "xsd" or "go".
+func ParseNamingStyle(style string) (NamingStyle, error) {
+	switch strings.ToLower(style) {
+	case "", "xsd":
+		return NamingXSD, nil
+	case "go":
+		return NamingGo, nil
+	}
+	return NamingXSD, fmt.Errorf("unknown naming style %q", style)
+}
+
+// InlineTypeNaming selects how complex types declared inline, within an
+// element of another complex type, are generated.
+type InlineTypeNaming int
+
+const (
+	// InlineAnonymous generates an anonymous struct for each of them. This is
+	// the default.
+	InlineAnonymous InlineTypeNaming = iota
+
+	// InlineUnderscore generates a named type for each of them, named after
+	// the enclosing type and the element joined by an underscore:
+	// Parent_Child.
+	InlineUnderscore
+
+	// InlineConcat works like InlineUnderscore without the underscore:
+	// ParentChild.
+	InlineConcat
+)
+
+// ParseInlineTypeNaming parses the name of an inline type naming scheme, as
+// used by the gowsdl command: "anonymous", "underscore" or "concat".
+func ParseInlineTypeNaming(scheme string) (InlineTypeNaming, error) {
+	switch strings.ToLower(scheme) {
+	case "", "anonymous":
+		return InlineAnonymous, nil
+	case "underscore":
+		return InlineUnderscore, nil
+	case "concat":
+		return InlineConcat, nil
+	}
+	return InlineAnonymous, fmt.Errorf("unknown inline type naming %q", scheme)
+}
+
+// Naming configures the Go identifiers of generated code. XML tags always
+// keep the names used by the WSDL.
+type Naming struct {
+	Style NamingStyle
+
+	// Initialisms are words kept in upper case by NamingGo, in addition to
+	// the ones golint knows about, such as ID, URL, HTTP or XML.
+	Initialisms []string
+
+	// Types overrides the Go names of types, keyed by the XSD name of the
+	// simple type, complex type or element.
+	Types map[string]string
+
+	// Fields overrides the Go names of struct fields, keyed by the XSD name
+	// of the enclosing type or element and the XSD name of the field,
+	// separated by a dot: "Customer.customer_id".
+	Fields map[string]string
+
+	// Operations overrides the Go method names of operations, keyed by
+	// operation name or by port type and operation name separated by a dot.
+	Operations map[string]string
+
+	// InlineTypes selects whether complex types declared inline get named
+	// types instead of anonymous structs, and how these are named.
+	InlineTypes InlineTypeNaming
+}
+
+func (n *Naming) validate() error {
+	for _, overrides := range []map[string]string{n.Types, n.Fields, n.Operations} {
+		for key, name := range overrides {
+			if !token.IsIdentifier(name) {
+				return fmt.Errorf("name override %s: %q is not a valid Go identifier", key, name)
+			}
+		}
+	}
+	return nil
+}
+
+// commonInitialisms are the initialisms golint expects in upper case.
+var commonInitialisms = []string{
+	"ACL", "API", "ASCII", "CPU", "CSS", "DNS", "EOF", "GUID", "HTML", "HTTP",
+	"HTTPS", "ID", "IP", "JSON", "LHS", "QPS", "RAM", "RHS", "RPC", "SLA",
+	"SMTP", "SOAP", "SQL", "SSH", "TCP", "TLS", "TTL", "UDP", "UI", "UID",
+	"UUID", "URI", "URL", "UTF8", "VM", "WSDL", "XML", "XMPP", "XSRF", "XSS",
+}
+
+// splitWords splits name into words at non alphanumeric characters and at
+// case changes, so that "getHTTPResponse_v2" gives get, HTTP, Response, v2.
+func splitWords(name string) []string {
+	var words []string
+	var word []rune
+
+	runes := []rune(name)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if len(word) > 0 {
+				words = append(words, string(word))
+				word = nil
+			}
+			continue
+		}
+		if len(word) > 0 && unicode.IsUpper(r) {
+			prev := word[len(word)-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if !unicode.IsUpper(prev) || nextIsLower {
+				words = append(words, string(word))
+				word = nil
+			}
+		}
+		word = append(word, r)
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+
+	return words
+}
+
+// camelCase joins the words of name in CamelCase, keeping initialisms in
+// upper case. The first word is capitalized too when exported is set.
+func camelCase(name string, initialisms map[string]bool, exported bool) string {
+	for k, v := range specialCharacterMapping {
+		name = strings.ReplaceAll(name, k, v)
+	}
+
+	words := splitWords(name)
+	if len(words) == 0 {
+		if exported {
+			return "EmptyString"
+		}
+		return "emptyString"
+	}
+
+	var b strings.Builder
+	for i, word := range words {
+		upper := strings.ToUpper(word)
+		first := []rune(word)[0]
+
+		switch {
+		case i == 0 && !exported && unicode.IsLower(first):
+			if initialisms[upper] {
+				word = strings.ToLower(word)
+			}
+		case initialisms[upper]:
+			word = upper
+		case word == upper:
+			word = makePublic(strings.ToLower(word))
+		default:
+			word = makePublic(word)
+		}
+
+		// Keep digits of adjacent words apart: v1_14 is V1_14, not V114.
+		if i > 0 && unicode.IsDigit(first) {
+			prev := []rune(words[i-1])
+			if unicode.IsDigit(prev[len(prev)-1]) {
+				b.WriteString("_")
+			}
+		}
+		b.WriteString(word)
+	}
+
+	return b.String()
+}
+
+// Kinds of XSD declarations that are referenced by name from other
+// declarations. Types are looked up for type="..." and base="..."
+// attributes, elements for ref="..." attributes and WSDL message parts.
+const (
+	kindType    = "type"
+	kindElement = "element"
+)
+
+// predeclaredIdentifiers lists Go's universe block. Declaring any of them at
+// package level shadows the builtin and breaks generated code using it.
+var predeclaredIdentifiers = []string{
+	"any", "bool", "byte", "comparable", "complex64", "complex128", "error",
+	"float32", "float64", "int", "int8", "int16", "int32", "int64", "rune",
+	"string", "uint", "uint8", "uint16", "uint32", "uint64", "uintptr",
+	"true", "false", "iota", "nil",
+	"append", "cap", "clear", "close", "complex", "copy", "delete", "imag",
+	"len", "make", "max", "min", "new", "panic", "print", "println", "real",
+	"recover",
+}
+
+// generatedIdentifiers lists package level identifiers declared by the
+// header and server templates, as well as the packages they import.
+var generatedIdentifiers = []string{
+	// header_tmpl.go
+	"AnyType", "AnyURI", "NCName",
+	"context", "xml", "time", "soap",
+	// server_tmpl.go and server_header_tmpl.go
+	"WSDLUndefinedError", "SOAPEnvelopeRequest", "SOAPBodyRequest",
+	"SOAPEnvelopeResponse", "SOAPBodyResponse", "NewSOAPEnvelopResponse",
+	"Fault", "Endpoint", "wsdl",
+	"fmt", "errors", "reflect", "strings", "http",
+}
+
+// scope is a Go declaration space, such as the package block or the fields
+// and methods of a struct.
+type scope struct {
+	name  string
+	owner map[string]string
+	// keys holds the JSON keys taken by the fields of a struct.
+	keys map[string]bool
+}
+
+func newScope(name string) *scope {
+	return &scope{
+		name:  name,
+		owner: make(map[string]string),
+		keys:  make(map[string]bool),
+	}
+}
+
+// reserve marks identifier as taken by owner, without any collision check.
+func (s *scope) reserve(identifier, owner string) {
+	if _, taken := s.owner[identifier]; !taken {
+		s.owner[identifier] = owner
+	}
+}
+
+func (s *scope) taken(identifier string) bool {
+	_, taken := s.owner[identifier]
+	return taken
+}
+
+// candidate returns the n-th alternative for identifier: identifier with
+// suffix appended, followed by an increasing number if that is taken too.
+func candidate(identifier, suffix string, n int) string {
+	if n == 0 {
+		return identifier
+	}
+	if suffix == "" {
+		return identifier + strconv.Itoa(n+1)
+	}
+	if n == 1 {
+		return identifier + suffix
+	}
+	return identifier + suffix + strconv.Itoa(n)
+}
+
+// portTypeNames holds the names generated for a WSDL port type.
+type portTypeNames struct {
+	Interface   string
+	Impl        string
+	Constructor string
+}
+
+// serverOperation is an operation dispatched by the generated server.
+type serverOperation struct {
+	RequestType  string
+	ResponseType string
+	Field        string
+	Method       string
+}
+
+// symbolKey identifies an XSD declaration of a kind, by qualified name.
+type symbolKey struct {
+	kind string
+	name xml.Name
+}
+
+// symbolTable holds the Go identifiers assigned to everything the templates
+// declare. Names are assigned in one deterministic pass over the parsed WSDL,
+// so that collisions between types, fields, constants and methods are
+// resolved before any code is rendered. Every rename is logged.
+type symbolTable struct {
+	wsdl          *WSDL
+	makePublicFn  func(string) string
+	exportAll     bool
+	unknownFields bool
+	naming        Naming
+	occurrences   Occurrences
+	initialisms   map[string]bool
+	overridden    map[string]bool
+
+	// g records the diagnostics about declarations.
+	g *GoWSDL
+
+	pkg       *scope
+	names     map[interface{}]string
+	jsonKeys  map[interface{}]string
+	types     map[*XSDElement]string
+	refs      map[symbolKey]string
+	consts    map[string]string
+	portTypes map[*WSDLPortType]*portTypeNames
+	server    []*serverOperation
+
+	// locals holds the name first declared for each kind and local name,
+	// for references whose prefix does not resolve to the namespace of the
+	// declaration.
+	locals map[string]string
+	// xmlns holds the namespace declarations of the schemas of each target
+	// namespace, references of which are resolved with them.
+	xmlns map[string]map[string]string
+	// attrTypes holds the types of the fields generated for attributes.
+	attrTypes map[*XSDAttribute]string
+	// imported holds a type of each package the types of a namespace are
+	// taken from, by import path.
+	imported map[string]string
+
+	// Named inline types, by schema in declaration order.
+	schema      *XSDSchema
+	inlineTypes map[*XSDSchema][]*XSDElement
+	inlineNames map[*XSDElement]string
+
+	// Fields holding values of other generated types, and the ones that
+	// need a pointer since they close a recursive type.
+	edges     map[string][]*valueEdge
+	edgeOrder []string
+	indirect  map[*XSDElement]bool
+}
+
+func newSymbolTable(g *GoWSDL) *symbolTable {
+	st := &symbolTable{
+		wsdl:          g.wsdl,
+		makePublicFn:  g.makePublicFn,
+		exportAll:     g.exportAllTypes,
+		unknownFields: g.unknownFields,
+		naming:        g.naming,
+		occurrences:   g.occurrences,
+		g:             g,
+		initialisms:   make(map[string]bool),
+		overridden:    make(map[string]bool),
+		pkg:           newScope("package"),
+		names:         make(map[interface{}]string),
+		jsonKeys:      make(map[interface{}]string),
+		types:         make(map[*XSDElement]string),
+		refs:          make(map[symbolKey]string),
+		locals:        make(map[string]string),
+		xmlns:         make(map[string]map[string]string),
+		attrTypes:     make(map[*XSDAttribute]string),
+		imported:      make(map[string]string),
+		consts:        make(map[string]string),
+		portTypes:     make(map[*WSDLPortType]*portTypeNames),
+		inlineTypes:   make(map[*XSDSchema][]*XSDElement),
+		inlineNames:   make(map[*XSDElement]string),
+		edges:         make(map[string][]*valueEdge),
+		indirect:      make(map[*XSDElement]bool),
+	}
+
+	for _, initialism := range commonInitialisms {
+		st.initialisms[initialism] = true
+	}
+	for _, initialism := range g.naming.Initialisms {
+		st.initialisms[strings.ToUpper(initialism)] = true
+	}
+
+	for _, id := range predeclaredIdentifiers {
+		st.pkg.reserve(id, "predeclared identifier")
+	}
+	for _, id := range generatedIdentifiers {
+		st.pkg.reserve(id, "generated helper")
+	}
+
+	var schemas, imported []*XSDSchema
+	for _, schema := range g.wsdl.Types.Schemas {
+		if _, ok := g.namespacePackage(schema.TargetNamespace); ok {
+			imported = append(imported, schema)
+		} else {
+			schemas = append(schemas, schema)
+		}
+	}
+	for _, schema := range g.wsdl.Types.Schemas {
+		prefixes := st.xmlns[schema.TargetNamespace]
+		if prefixes == nil {
+			prefixes = make(map[string]string)
+			st.xmlns[schema.TargetNamespace] = prefixes
+		}
+		for prefix, ns := range schema.Xmlns {
+			if _, ok := prefixes[prefix]; !ok {
+				prefixes[prefix] = ns
+			}
+		}
+	}
+
+	// Named types keep their names first, since they are referenced the
+	// most, then come elements, enumeration constants and port types.
+	for _, schema := range schemas {
+		for _, simpleType := range schema.SimpleType {
+			st.declareType(kindType, schema, simpleType.Name, simpleType, "simpleType", "Type")
+		}
+		for _, complexType := range schema.ComplexTypes {
+			st.declareType(kindType, schema, complexType.Name, complexType, "complexType", "Type")
+		}
+	}
+	for _, schema := range schemas {
+		for _, el := range schema.Elements {
+			st.declareElement(schema, el)
+		}
+	}
+	for _, schema := range imported {
+		importPath, _ := g.namespacePackage(schema.TargetNamespace)
+		st.declareImported(schema, importPath)
+	}
+	for _, schema := range schemas {
+		for _, simpleType := range schema.SimpleType {
+			st.declareEnumeration(schema, st.names[simpleType], simpleType)
+		}
+		for _, el := range schema.Elements {
+			if el.Type == "" && el.SimpleType != nil {
+				st.declareEnumeration(schema, st.names[el], el.SimpleType)
+			}
+		}
+	}
+	for _, schema := range schemas {
+		st.schema = schema
+		for _, el := range schema.Elements {
+			if el.Type == "" && el.ComplexType != nil {
+				st.declareFields(el.Name, st.names[el], el.ComplexType, false)
+			} else if el.Type != "" {
+				// type Element Type
+				if goType := removePointerFromType(st.resolveType(schema.Xmlns, el.Type, false)); goType != st.names[el] {
+					st.addValueEdge(st.names[el], goType, nil)
+				}
+			}
+		}
+		for _, complexType := range schema.ComplexTypes {
+			st.declareFields(complexType.Name, st.names[complexType], complexType, false)
+		}
+	}
+	st.breakCycles()
+	for _, portType := range g.wsdl.PortTypes {
+		st.declarePortType(portType)
+	}
+	st.declareServer()
+
+	st.checkOverrides("type", st.naming.Types)
+	st.checkOverrides("field", st.naming.Fields)
+	st.checkOverrides("operation", st.naming.Operations)
+
+	return st
+}
+
+// identifier returns the Go identifier for an XSD or WSDL name. The default
+// naming style converts name with legacy, the Go naming style uses CamelCase
+// and exports the identifier if exported is set.
+func (st *symbolTable) identifier(name string, exported bool, legacy func(string) string) string {
+	if st.naming.Style != NamingGo {
+		return legacy(name)
+	}
+
+	id := camelCase(name, st.initialisms, exported)
+	if value, reserved := reservedWords[id]; reserved {
+		return value
+	}
+	return id
+}
+
+// typeIdentifier returns the Go identifier for a type, element or port type.
+func (st *symbolTable) typeIdentifier(name string) string {
+	return st.identifier(name, st.exportAll, func(name string) string {
+		return st.makePublicFn(replaceReservedWords(name))
+	})
+}
+
+// override returns the name override registered under any of keys.
+func (st *symbolTable) override(overrides map[string]string, keys ...string) (string, bool) {
+	for _, key := range keys {
+		if name, ok := overrides[key]; ok {
+			st.overridden[key] = true
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// checkOverrides warns about overrides that did not match anything, which
+// are likely typos.
+func (st *symbolTable) checkOverrides(what string, overrides map[string]string) {
+	var unused []string
+	for key := range overrides {
+		if !st.overridden[key] {
+			unused = append(unused, key)
+		}
+	}
+	sort.Strings(unused)
+	for _, key := range unused {
+		st.g.warnf(construct{}, "Name override for %s %s does not match anything", what, key)
+	}
+}
+
+// rename records that identifier was declared as renamed because of a
+// collision within the given scope.
+func (st *symbolTable) rename(s *scope, at construct, what, identifier, renamed string) {
+	st.g.warnf(at, "Renamed %s to %s: %s is already declared in %s scope by %s",
+		what, renamed, identifier, s.name, s.owner[identifier])
+}
+
+// declare assigns identifier, or the first free candidate derived from it,
+// to owner within s and returns it. Renames are reported at the construct
+// declaring owner.
+func (st *symbolTable) declare(s *scope, identifier, suffix, owner string, at construct) string {
+	name := identifier
+	for n := 1; s.taken(name); n++ {
+		name = candidate(identifier, suffix, n)
+	}
+	if name != identifier {
+		st.rename(s, at, owner, identifier, name)
+	}
+	s.owner[name] = owner
+	return name
+}
+
+// declareKey assigns key, or the first free candidate derived from it, as
+// the JSON key of the field generated for node within s. Fields renamed by
+// declare would otherwise keep the key of the field they collide with.
+func (st *symbolTable) declareKey(s *scope, node interface{}, key, suffix string) {
+	name := key
+	for n := 1; s.keys[name]; n++ {
+		name = candidate(key, suffix, n)
+	}
+	s.keys[name] = true
+	st.jsonKeys[node] = name
+}
+
+// declarePair works like declare but also requires identifier with the
+// given companion suffix to be free, as with generated Foo/FooContext
+// methods.
+func (st *symbolTable) declarePair(s *scope, identifier, suffix, companion, owner string, at construct) string {
+	name := identifier
+	for n := 1; s.taken(name) || s.taken(name+companion); n++ {
+		name = candidate(identifier, suffix, n)
+	}
+	if name != identifier {
+		st.rename(s, at, owner, identifier, name)
+	}
+	s.owner[name] = owner
+	s.owner[name+companion] = owner
+	return name
+}
+
+// declareRef records name as the Go name of the XSD declaration of the given
+// kind and qualified name, unless one is declared already.
+func (st *symbolTable) declareRef(kind string, xsdName xml.Name, name string) {
+	key := symbolKey{kind, xsdName}
+	if _, exists := st.refs[key]; !exists {
+		st.refs[key] = name
+	}
+	if _, exists := st.locals[kind+":"+xsdName.Local]; !exists {
+		st.locals[kind+":"+xsdName.Local] = name
+	}
+}
+
+func (st *symbolTable) declareType(kind string, schema *XSDSchema, xsdName string, node interface{}, what, suffix string) {
+	owner := fmt.Sprintf("%s %q", what, xsdName)
+	name, ok := st.override(st.naming.Types, xsdName)
+	if !ok {
+		name = st.typeIdentifier(xsdName)
+	}
+	name = st.declare(st.pkg, name, suffix, owner, st.g.at(schema, what, "name", xsdName))
+	st.names[node] = name
+	st.declareRef(kind, xml.Name{Space: schema.TargetNamespace, Local: xsdName}, name)
+}
+
+func (st *symbolTable) declareElement(schema *XSDSchema, el *XSDElement) {
+	if el.Type == "" {
+		if el.ComplexType != nil || el.SimpleType != nil {
+			st.declareType(kindElement, schema, el.Name, el, "element", "Element")
+		}
+		return
+	}
+
+	// An element of a type with the same Go name is not declared again,
+	// it is just an alias for the type.
+	name, ok := st.override(st.naming.Types, el.Name)
+	if !ok {
+		name = st.typeIdentifier(el.Name)
+	}
+	if name == removePointerFromType(st.resolveType(schema.Xmlns, el.Type, el.Nillable)) {
+		st.names[el] = name
+		st.declareRef(kindElement, xml.Name{Space: schema.TargetNamespace, Local: el.Name}, name)
+		return
+	}
+	st.declareType(kindElement, schema, el.Name, el, "element", "Element")
+}
+
+// declareImported declares the types and elements of schema, whose namespace
+// is mapped to the package importPath, under the names they are generated
+// with in that package.
+func (st *symbolTable) declareImported(schema *XSDSchema, importPath string) {
+	qualifier := path.Base(importPath)
+	identifier := func(xsdName string) string {
+		if name, ok := st.override(st.naming.Types, xsdName); ok {
+			return name
+		}
+		return st.typeIdentifier(xsdName)
+	}
+	declare := func(kind, xsdName string, node interface{}, name string) {
+		name = qualifier + "." + name
+		st.names[node] = name
+		st.declareRef(kind, xml.Name{Space: schema.TargetNamespace, Local: xsdName}, name)
+		if _, ok := st.imported[importPath]; !ok {
+			st.imported[importPath] = name
+		}
+	}
+
+	types := make(map[string]bool)
+	for _, simpleType := range schema.SimpleType {
+		name := identifier(simpleType.Name)
+		types[name] = true
+		declare(kindType, simpleType.Name, simpleType, name)
+	}
+	for _, complexType := range schema.ComplexTypes {
+		name := identifier(complexType.Name)
+		types[name] = true
+		declare(kindType, complexType.Name, complexType, name)
+	}
+	// Elements are declared like declareElement does, the types of the
+	// namespace keeping their names first.
+	for _, el := range schema.Elements {
+		if el.Type == "" && el.ComplexType == nil && el.SimpleType == nil {
+			continue
+		}
+		name := identifier(el.Name)
+		if el.Type != "" && qualifier+"."+name == removePointerFromType(st.resolveType(schema.Xmlns, el.Type, el.Nillable)) {
+			declare(kindElement, el.Name, el, name)
+			continue
+		}
+		if types[name] {
+			name += "Element"
+		}
+		declare(kindElement, el.Name, el, name)
+	}
+}
+
+func (st *symbolTable) declareEnumeration(schema *XSDSchema, typeName string, simpleType *XSDSimpleType) {
+	for i, enum := range simpleType.Restriction.Enumeration {
+		owner := fmt.Sprintf("enumeration value %q of %s", enum.Value, typeName)
+		id := typeName + st.identifier(enum.Value, true, func(value string) string {
+			return st.makePublicFn(replaceReservedWords(value))
+		})
+		st.consts[typeName+"#"+strconv.Itoa(i)] = st.declare(st.pkg, id, "", owner,
+			st.g.at(schema, "enumeration", "value", enum.Value))
+	}
+}
+
+// declareFields assigns field names for the struct generated for complexType,
+// declared by the type or element named owner, with Go name typeName. Inline
+// complex types are declared recursively, each with its own scope.
+func (st *symbolTable) declareFields(owner, typeName string, complexType *XSDComplexType, inline bool) {
+	s := newScope("struct " + owner)
+	s.reserve("XMLName", "generated field")
+
+	declareElements := func(elements []*XSDElement, inChoice bool) {
+		for _, el := range elements {
+			st.declareElementField(s, owner, typeName, el)
+			if el.Ref != "" || el.Type != "" || el.SimpleType != nil {
+				st.types[el] = st.elementType(st.schema.Xmlns, el, inChoice)
+				st.addValueEdge(typeName, st.types[el], el)
+			}
+		}
+	}
+
+	if base := complexType.ComplexContent.Extension.Base; base != "" {
+		s.reserve(removePointerFromType(st.resolveType(st.schema.Xmlns, base, false)), "embedded base type")
+		declareElements(complexType.ComplexContent.Extension.Sequence, false)
+		declareElements(complexType.ComplexContent.Extension.Choice, true)
+		declareElements(complexType.ComplexContent.Extension.SequenceChoice, true)
+		st.declareAttributeFields(s, owner, complexType.ComplexContent.Extension.Attributes)
+	} else if complexType.SimpleContent.Extension.Base != "" {
+		s.reserve("Value", "generated field")
+		st.declareAttributeFields(s, owner, complexType.SimpleContent.Extension.Attributes)
+	} else {
+		if len(complexType.Any) > 0 && (!inline || st.unknownFields) {
+			s.reserve("Items", "generated field")
+			s.keys["items"] = true
+		}
+		declareElements(complexType.Sequence, false)
+		declareElements(complexType.Choice, true)
+		declareElements(complexType.SequenceChoice, true)
+		declareElements(complexType.All, false)
+		st.declareAttributeFields(s, owner, complexType.Attributes)
+	}
+
+	if st.unknownFields {
+		s.reserve("UnknownElements", "generated field")
+		s.reserve("UnknownAttrs", "generated field")
+	}
+}
+
+func (st *symbolTable) declareElementField(s *scope, owner, typeName string, el *XSDElement) {
+	xsdName := el.Name
+	if el.Ref != "" {
+		xsdName = removeNS(el.Ref)
+	}
+
+	name, ok := st.override(st.naming.Fields, owner+"."+xsdName)
+	switch {
+	case ok:
+	case el.Ref != "":
+		name = st.typeIdentifier(xsdName)
+	case el.Type != "":
+		name = st.identifier(xsdName, true, func(name string) string {
+			return makePublic(replaceAttrReservedWords(name))
+		})
+	case el.SimpleType != nil:
+		name = st.identifier(xsdName, true, func(name string) string {
+			return makePublic(normalize(name))
+		})
+	default:
+		name = st.typeIdentifier(xsdName)
+	}
+	if el.Ref == "" && el.Type == "" && el.SimpleType == nil && el.ComplexType != nil {
+		st.declareInlineType(typeName, el)
+	}
+	st.names[el] = st.declare(s, name, "", fmt.Sprintf("element %q", xsdName), st.elementAt(el))
+	st.declareKey(s, el, xsdName, "")
+}
+
+// elementAt returns the construct of el, a local element of the schema
+// whose fields are being declared.
+func (st *symbolTable) elementAt(el *XSDElement) construct {
+	if el.Ref != "" {
+		return st.g.at(st.schema, "element", "ref", el.Ref)
+	}
+	return st.g.at(st.schema, "element", "name", el.Name)
+}
+
+// declareInlineType declares the fields of the complex type declared inline
+// by el, and a named type for it unless anonymous structs are generated.
+func (st *symbolTable) declareInlineType(parent string, el *XSDElement) {
+	if st.naming.InlineTypes == InlineAnonymous {
+		// The fields of anonymous structs belong to the parent type as
+		// far as recursive types are concerned.
+		st.declareFields(el.Name, parent, el.ComplexType, true)
+		return
+	}
+
+	sep := ""
+	if st.naming.InlineTypes == InlineUnderscore {
+		sep = "_"
+	}
+	owner := fmt.Sprintf("inline type of element %q", el.Name)
+	name := st.declare(st.pkg, parent+sep+st.identifier(el.Name, true, func(name string) string {
+		return makePublic(replaceReservedWords(name))
+	}), "", owner, st.g.at(st.schema, "element", "name", el.Name))
+
+	st.inlineNames[el] = name
+	st.inlineTypes[st.schema] = append(st.inlineTypes[st.schema], el)
+	st.declareFields(el.Name, name, el.ComplexType, true)
+	st.addValueEdge(parent, inlineShape(el)+name, el)
+}
+
+func (st *symbolTable) declareAttributeFields(s *scope, owner string, attrs []*XSDAttribute) {
+	for _, attr := range attrs {
+		name, ok := st.override(st.naming.Fields, owner+"."+attr.Name)
+		if !ok {
+			name = st.identifier(attr.Name, true, func(name string) string {
+				return makePublic(normalize(name))
+			})
+		}
+		st.names[attr] = st.declare(s, name, "Attr", fmt.Sprintf("attribute %q", attr.Name),
+			st.g.at(st.schema, "attribute", "name", attr.Name))
+		st.declareKey(s, attr, attr.Name, "Attr")
+		st.attrTypes[attr] = st.resolveAttributeType(st.schema.Xmlns, attr)
+	}
+}
+
+func (st *symbolTable) declarePortType(portType *WSDLPortType) {
+	owner := fmt.Sprintf("portType %q", portType.Name)
+	exported := st.identifier(portType.Name, st.exportAll, st.makePublicFn)
+
+	at := st.g.at(portType, "portType", "name", portType.Name)
+	iface := st.declare(st.pkg, exported, "PortType", owner, at)
+	st.portTypes[portType] = &portTypeNames{
+		Interface:   iface,
+		Impl:        st.declare(st.pkg, makePrivate(iface), "", owner, at),
+		Constructor: st.declare(st.pkg, "New"+iface, "", owner, at),
+	}
+
+	methods := newScope(owner)
+	methods.reserve("client", "generated field")
+	for _, op := range portType.Operations {
+		name, ok := st.override(st.naming.Operations, portType.Name+"."+op.Name, op.Name)
+		if !ok {
+			name = st.identifier(op.Name, st.exportAll, func(name string) string {
+				return replaceReservedWords(st.makePublicFn(name))
+			})
+		}
+		st.names[op] = st.declarePair(methods, name, "", "Context", fmt.Sprintf("operation %q", op.Name),
+			st.g.at(op, "operation", "name", op.Name))
+	}
+}
+
+// declareServer collects the operations the generated server dispatches.
+// The server tells operations apart by the Go type of their request, so
+// only the first operation taking a given request type is served.
+func (st *symbolTable) declareServer() {
+	requests := newScope("SOAPBodyRequest")
+	requests.reserve("XMLName", "generated field")
+	responses := newScope("SOAPBodyResponse")
+	responses.reserve("XMLName", "generated field")
+	responses.reserve("Fault", "generated field")
+
+	seen := make(map[string]string)
+	for _, portType := range st.wsdl.PortTypes {
+		for _, op := range portType.Operations {
+			request := st.messageType(op.Input.Message)
+			response := st.messageType(op.Output.Message)
+			if request == "" || response == "" {
+				st.g.warnf(st.g.at(op, "operation", "name", op.Name),
+					"Operation %s has no request or response type, the server will not handle it", op.Name)
+				continue
+			}
+			if other, exists := seen[request]; exists {
+				st.g.warnf(st.g.at(op, "operation", "name", op.Name),
+					"Operation %s takes %s like operation %s, the server will only handle %s", op.Name, request, other, other)
+				continue
+			}
+			seen[request] = op.Name
+
+			// Types XSD types are mapped to may be qualified by their
+			// package, which the field is not.
+			owner := fmt.Sprintf("operation %q", op.Name)
+			field := st.declarePair(requests, request[strings.LastIndex(request, ".")+1:], "", "Func", owner,
+				st.g.at(op, "operation", "name", op.Name))
+			responses.reserve(field, owner)
+			st.server = append(st.server, &serverOperation{
+				RequestType:  request,
+				ResponseType: response,
+				Field:        field,
+				Method:       field + "Func",
+			})
+		}
+	}
+}
+
+// lookup returns the Go name declared for the XSD name of the given kind,
+// resolved with the namespace declarations xmlns in scope. Names whose
+// prefix does not resolve to a declaration, as happens with loosely written
+// schemas, or that are looked up without xmlns, are resolved by local name.
+func (st *symbolTable) lookup(xmlns map[string]string, kind, xsdName string) (string, bool) {
+	if xmlns != nil {
+		if name, ok := st.refs[symbolKey{kind, xmlName(xmlns, xsdName)}]; ok {
+			return name, true
+		}
+	}
+	name, ok := st.locals[kind+":"+stripns(xsdName)]
+	return name, ok
+}
+
+// toGoType maps a XSD type reference to the Go type to use for it.
+func (st *symbolTable) toGoType(xsdType string, nillable bool) string {
+	return st.resolveType(nil, xsdType, nillable)
+}
+
+// resolveType maps a XSD type reference, made where the namespace
+// declarations xmlns are in scope, to the Go type to use for it.
+func (st *symbolTable) resolveType(xmlns map[string]string, xsdType string, nillable bool) string {
+	if goType, ok := st.g.mappedType(xmlns, xsdType); ok {
+		if nillable {
+			return "*" + goType
+		}
+		return goType
+	}
+	if _, builtin := xsd2GoTypes[strings.ToLower(stripns(xsdType))]; !builtin {
+		if name, ok := st.lookup(xmlns, kindType, xsdType); ok {
+			return "*" + name
+		}
+		if name, ok := st.lookup(xmlns, kindElement, xsdType); ok {
+			return "*" + name
+		}
+	}
+	return toGoType(xsdType, nillable)
+}
+
+// toGoRefType maps a XSD element reference to the Go type to use for it.
+func (st *symbolTable) toGoRefType(ref string, nillable bool) string {
+	return st.resolveRefType(nil, ref, nillable)
+}
+
+// resolveRefType is like resolveType, for element references.
+func (st *symbolTable) resolveRefType(xmlns map[string]string, ref string, nillable bool) string {
+	if _, builtin := xsd2GoTypes[strings.ToLower(stripns(ref))]; !builtin {
+		if name, ok := st.lookup(xmlns, kindElement, ref); ok {
+			return "*" + name
+		}
+	}
+	return st.resolveType(xmlns, ref, nillable)
+}
+
+// typeName returns the Go name of a type declared for an XSD node.
+func (st *symbolTable) typeName(node interface{}) string {
+	return st.names[node]
+}
+
+// fieldName returns the Go field name declared for an element or attribute.
+func (st *symbolTable) fieldName(node interface{}) string {
+	return st.names[node]
+}
+
+// jsonKey returns the JSON key of the field generated for an element or
+// attribute.
+func (st *symbolTable) jsonKey(node interface{}) string {
+	if key, ok := st.jsonKeys[node]; ok {
+		return key
+	}
+	switch node := node.(type) {
+	case *XSDElement:
+		if node.Ref != "" {
+			return removeNS(node.Ref)
+		}
+		return node.Name
+	case *XSDAttribute:
+		return node.Name
+	}
+	return ""
+}
+
+// enumName returns the name of the constant for the i-th enumeration value
+// of the type named typeName.
+func (st *symbolTable) enumName(typeName string, i int) string {
+	return st.consts[typeName+"#"+strconv.Itoa(i)]
+}
+
+// portTypeNames returns the names of the interface, implementation and
+// constructor generated for portType.
+func (st *symbolTable) portTypeNames(portType *WSDLPortType) *portTypeNames {
+	return st.portTypes[portType]
+}
+
+// operationName returns the method name generated for op.
+func (st *symbolTable) operationName(op *WSDLOperation) string {
+	return st.names[op]
+}
+
+// messageType returns the Go type of the request or response of an operation,
+// given the name of its message. Only the first message part is considered,
+// as this assumes document/literal wrapped WS-I style.
+func (st *symbolTable) messageType(message string) string {
+	msg := st.g.index.messages[stripns(message)]
+	if msg == nil {
+		return ""
+	}
+
+	part := msg.Parts[0]
+	if part.Type != "" {
+		return removePointerFromType(st.resolveType(st.wsdl.Xmlns, part.Type, false))
+	}
+
+	el, ns := st.g.index.element(st.wsdl.Xmlns, part.Element)
+	if el == nil {
+		return ""
+	}
+	if el.Type != "" {
+		return removePointerFromType(st.resolveType(st.xmlns[ns], el.Type, false))
+	}
+	return st.names[el]
+}
+
+// inlineTypeName returns the name of the type generated for the complex type
+// declared inline by el, or "" if it is generated as an anonymous struct.
+func (st *symbolTable) inlineTypeName(el *XSDElement) string {
+	return st.inlineNames[el]
+}
+
+// inlineTypesOf returns the elements of schema whose inline complex types
+// are generated as named types.
+func (st *symbolTable) inlineTypesOf(schema *XSDSchema) []*XSDElement {
+	return st.inlineTypes[schema]
+}
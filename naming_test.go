@@ -0,0 +1,161 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCamelCase(t *testing.T) {
+	initialisms := map[string]bool{"ID": true, "URL": true, "HTTP": true, "XML": true, "SKU": true}
+
+	tests := []struct {
+		name     string
+		exported bool
+		expected string
+	}{
+		{"CustomerId", true, "CustomerID"},
+		{"HttpUrl", true, "HTTPURL"},
+		{"get_account_info", true, "GetAccountInfo"},
+		{"get-account-info", true, "GetAccountInfo"},
+		{"account.info", true, "AccountInfo"},
+		{"ACCOUNT_INFO", true, "AccountInfo"},
+		{"getHTTPResponse", true, "GetHTTPResponse"},
+		{"xmlPayload", true, "XMLPayload"},
+		{"xmlPayload", false, "xmlPayload"},
+		{"id", false, "id"},
+		{"item_sku", true, "ItemSKU"},
+		{"v1_14", true, "V1_14"},
+		{"Base64Binary", true, "Base64Binary"},
+		{"C++", true, "CPlusPlus"},
+		{"", true, "EmptyString"},
+	}
+	for _, test := range tests {
+		actual := camelCase(test.name, initialisms, test.exported)
+		if actual != test.expected {
+			t.Errorf("camelCase(%q, %v) got %s want %s", test.name, test.exported, actual, test.expected)
+		}
+	}
+}
+
+func TestNamingGoStyleAndOverrides(t *testing.T) {
+	naming := Naming{
+		Style:       NamingGo,
+		Initialisms: []string{"Mnb"},
+		Types:       map[string]string{"ResponseStatus": "Status"},
+		Fields:      map[string]string{"ResponseStatus.responseCode": "Code"},
+		Operations:  map[string]string{"MNBArfolyamServiceType.GetInfoSoap": "Info"},
+	}
+	g, err := NewGoWSDL("fixtures/test.wsdl", "myservice", false, true, WithNaming(naming))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := g.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := getTypeDeclaration(resp, "GetInfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(actual, "ID\tstring\t`xml:\"Id,omitempty\" json:\"Id,omitempty\"`") {
+		t.Errorf("initialism not applied to field Id, got %s", actual)
+	}
+
+	actual, err = getTypeDeclaration(resp, "Status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(actual, "Code\tstring\t`xml:\"http://www.mnb.hu/webservices/ responseCode,attr,omitempty\"") {
+		t.Errorf("field override not applied, got %s", actual)
+	}
+
+	ops := string(resp["operations"])
+	if !strings.Contains(ops, "type MNBArfolyamServiceType interface") {
+		t.Errorf("port type not named using initialisms, got %s", ops)
+	}
+	if !strings.Contains(ops, "Info (request *GetInfo) (*GetInfoResponse, error)") {
+		t.Errorf("operation override not applied, got %s", ops)
+	}
+}
+
+func TestNamingOverrideMustBeIdentifier(t *testing.T) {
+	naming := Naming{Types: map[string]string{"ResponseStatus": "Response Status"}}
+	_, err := NewGoWSDL("fixtures/test.wsdl", "myservice", false, true, WithNaming(naming))
+	if err == nil {
+		t.Error("expected an error for an invalid override")
+	}
+}
+
+const qualifiedNamesWSDL = `<?xml version="1.0" encoding="UTF-8"?>
+<definitions name="Orders" targetNamespace="urn:orders:wsdl"
+  xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"
+  xmlns:tns="urn:orders:wsdl"
+  xmlns:a="urn:a">
+  <types>
+    <xs:schema targetNamespace="urn:a" elementFormDefault="qualified"
+      xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:a="urn:a" xmlns:b="urn:b">
+      <xs:import namespace="urn:b"/>
+      <xs:complexType name="Item">
+        <xs:sequence><xs:element name="name" type="xs:string"/></xs:sequence>
+      </xs:complexType>
+      <xs:element name="Order" type="a:Item"/>
+      <xs:element name="order" type="b:Item"/>
+      <xs:element name="Wrapper">
+        <xs:complexType><xs:sequence><xs:element name="item" type="b:Item"/></xs:sequence></xs:complexType>
+      </xs:element>
+    </xs:schema>
+    <xs:schema targetNamespace="urn:b" elementFormDefault="qualified"
+      xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:b="urn:b">
+      <xs:complexType name="Item">
+        <xs:sequence><xs:element name="count" type="xs:int"/></xs:sequence>
+      </xs:complexType>
+    </xs:schema>
+  </types>
+  <message name="GetRequest"><part name="body" element="a:order"/></message>
+  <message name="GetResponse"><part name="body" element="a:Wrapper"/></message>
+  <portType name="OrdersPortType">
+    <operation name="Get">
+      <input message="tns:GetRequest"/>
+      <output message="tns:GetResponse"/>
+    </operation>
+  </portType>
+  <binding name="OrdersBinding" type="tns:OrdersPortType">
+    <soap:binding style="document" transport="http://schemas.xmlsoap.org/soap/http"/>
+    <operation name="Get">
+      <soap:operation soapAction="urn:Get"/>
+      <input><soap:body use="literal"/></input>
+      <output><soap:body use="literal"/></output>
+    </operation>
+  </binding>
+</definitions>`
+
+func TestNamingQualifiedReferences(t *testing.T) {
+	g, err := New("orders.wsdl", WithLoader("", MapLoader{"orders.wsdl": []byte(qualifiedNamesWSDL)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := g.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code := result.Files[0].Content
+	for _, want := range []string{
+		"type Order Item",
+		"type OrderElement ItemType",
+		"Item *ItemType `xml:\"item,omitempty\"",
+		"Get(request *ItemType) (*Wrapper, error)",
+	} {
+		if !bytes.Contains(code, []byte(want)) {
+			t.Errorf("generated code does not contain %q:\n%s", want, code)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Occurrences configures how minOccurs, maxOccurs and use shape the Go types
+// of generated fields. Elements that may occur more than once always become
+// slices.
+type Occurrences struct {
+	// OptionalPointers makes optional elements and attributes of simple
+	// types pointers, so that an absent value can be told apart from a
+	// zero value.
+	OptionalPointers bool
+
+	// RequiredValues makes required elements and attributes of named types
+	// values instead of pointers.
+	RequiredValues bool
+}
+
+// isRepeated reports whether maxOccurs allows more than one occurrence.
+func isRepeated(maxOccurs string) bool {
+	if maxOccurs == "unbounded" {
+		return true
+	}
+	n, err := strconv.Atoi(maxOccurs)
+	return err == nil && n > 1
+}
+
+// isOptional reports whether minOccurs allows no occurrence at all.
+func isOptional(minOccurs string) bool {
+	return minOccurs == "0"
+}
+
+// shape wraps goType, as returned by toGoType, according to the occurrence
+// constraints of an element or attribute.
+func (o Occurrences) shape(goType string, repeated, optional, nillable bool) string {
+	if repeated {
+		return "[]" + goType
+	}
+
+	isPointer := strings.HasPrefix(goType, "*")
+	isSlice := strings.HasPrefix(goType, "[]")
+	switch {
+	case optional && o.OptionalPointers && !isPointer && !isSlice:
+		return "*" + goType
+	case !optional && o.RequiredValues && isPointer && !nillable:
+		return goType[1:]
+	}
+	return goType
+}
+
+// elementType returns the Go type of the field generated for el, which must
+// not declare a complex type inline, resolving its type with the namespace
+// declarations xmlns. Elements within a choice are optional.
+func (st *symbolTable) elementType(xmlns map[string]string, el *XSDElement, inChoice bool) string {
+	optional := inChoice || isOptional(el.MinOccurs)
+	repeated := isRepeated(el.MaxOccurs)
+
+	switch {
+	case el.Ref != "":
+		return st.occurrences.shape(st.resolveRefType(xmlns, el.Ref, el.Nillable), repeated, optional, el.Nillable)
+	case el.Type != "":
+		return st.occurrences.shape(st.resolveType(xmlns, el.Type, el.Nillable), repeated, optional, el.Nillable)
+	case el.SimpleType.List.ItemType != "":
+		// A list is a slice already, repeating it does not make it a
+		// slice of slices.
+		return "[]" + st.resolveType(xmlns, el.SimpleType.List.ItemType, false)
+	}
+	return st.occurrences.shape(st.resolveType(xmlns, el.SimpleType.Restriction.Base, false), repeated, optional, false)
+}
+
+// inlineShape returns what goes in front of the type of a field generated for
+// an element declaring a complex type inline: "[]" when it is repeated.
+// Inline types are struct values otherwise, like before.
+func inlineShape(el *XSDElement) string {
+	if isRepeated(el.MaxOccurs) {
+		return "[]"
+	}
+	return ""
+}
+
+// inlineShape is like the function of the same name, but gives a pointer
+// to the fields that close a recursive type.
+func (st *symbolTable) inlineShape(el *XSDElement) string {
+	if st.indirect[el] {
+		return "*"
+	}
+	return inlineShape(el)
+}
+
+// attributeType returns the Go type of the field generated for attr.
+func (st *symbolTable) attributeType(attr *XSDAttribute) string {
+	if goType, ok := st.g.fieldTypes[attr]; ok {
+		return goType
+	}
+	if goType, ok := st.attrTypes[attr]; ok {
+		return goType
+	}
+	return st.resolveAttributeType(nil, attr)
+}
+
+// resolveAttributeType returns the Go type of the field generated for attr,
+// resolving its type with the namespace declarations xmlns.
+func (st *symbolTable) resolveAttributeType(xmlns map[string]string, attr *XSDAttribute) string {
+	goType := "string"
+	if attr.Type != "" {
+		goType = st.resolveType(xmlns, attr.Type, false)
+	}
+	return st.occurrences.shape(goType, false, attr.Use != "required", false)
+}
+
+// fieldType returns the Go type of the field generated for el.
+func (st *symbolTable) fieldType(el *XSDElement) string {
+	if goType, ok := st.g.fieldTypes[el]; ok {
+		return goType
+	}
+	if goType, ok := st.types[el]; ok {
+		return goType
+	}
+	return st.elementType(nil, el, false)
+}
@@ -0,0 +1,360 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Operations selects the operations code is generated for. Patterns match
+// the name of an operation, or the name of its port type and its name
+// separated by a dot, as in OrdersPort.Get*. They are globs, as understood
+// by path.Match, or regular expressions between slashes, as in
+// /^(Get|List)Orders?$/.
+type Operations struct {
+	// Include selects the operations to generate, all of them when empty.
+	Include []string
+	// Exclude leaves out operations, included or not.
+	Exclude []string
+}
+
+// WithOperations generates code for the selected operations only, and only
+// for the types they need: those of the elements and types of the parts of
+// their messages, headers and faults, those these refer to, and so on, as
+// well as the types derived from them, which may stand in for them. Port
+// types left without operations are not generated either.
+func WithOperations(ops Operations) Option {
+	return func(g *GoWSDL) {
+		g.operations = &ops
+	}
+}
+
+// operationMatcher reports whether a pattern matches the name of an
+// operation, or its port type and name separated by a dot.
+type operationMatcher func(name string) bool
+
+func compileOperationPattern(pattern string) (operationMatcher, error) {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, fmt.Errorf("operation pattern %s: %v", pattern, err)
+		}
+		return re.MatchString, nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("operation pattern %s: %v", pattern, err)
+	}
+	return func(name string) bool {
+		ok, _ := path.Match(pattern, name)
+		return ok
+	}, nil
+}
+
+// compileOperations checks the patterns of g.operations and compiles them.
+func (g *GoWSDL) compileOperations() error {
+	if g.operations == nil {
+		return nil
+	}
+	compile := func(patterns []string) ([]operationMatcher, error) {
+		var matchers []operationMatcher
+		for _, pattern := range patterns {
+			m, err := compileOperationPattern(pattern)
+			if err != nil {
+				return nil, err
+			}
+			matchers = append(matchers, m)
+		}
+		return matchers, nil
+	}
+
+	var err error
+	if g.includeOps, err = compile(g.operations.Include); err != nil {
+		return err
+	}
+	g.excludeOps, err = compile(g.operations.Exclude)
+	return err
+}
+
+// selectOperations drops the operations that are not selected, and the port
+// types left without operations.
+func (g *GoWSDL) selectOperations() {
+	if g.operations == nil {
+		return
+	}
+	matches := func(matchers []operationMatcher, portType, op string) bool {
+		for _, m := range matchers {
+			if m(op) || m(portType+"."+op) {
+				return true
+			}
+		}
+		return false
+	}
+
+	t := &Transform{g}
+	portTypes := g.wsdl.PortTypes[:0]
+	for _, pt := range g.wsdl.PortTypes {
+		var dropped []string
+		for _, op := range pt.Operations {
+			if (len(g.includeOps) > 0 && !matches(g.includeOps, pt.Name, op.Name)) || matches(g.excludeOps, pt.Name, op.Name) {
+				dropped = append(dropped, op.Name)
+			}
+		}
+		for _, op := range dropped {
+			t.DropOperation(pt.Name, op)
+		}
+		if len(pt.Operations) > 0 || len(dropped) == 0 {
+			portTypes = append(portTypes, pt)
+		}
+	}
+	g.wsdl.PortTypes = portTypes
+}
+
+// declaration is a global declaration of a schema.
+type declaration struct {
+	schema *XSDSchema
+	node   interface{}
+}
+
+// pruneTypes drops the global elements, complex types and simple types of
+// the schemas that the operations left do not need. It runs once the
+// traversers have expanded model groups and resolved attribute references.
+func (g *GoWSDL) pruneTypes() {
+	if g.operations == nil {
+		return
+	}
+
+	messages := make(map[xml.Name]*WSDLMessage)
+	localMessages := make(map[string]*WSDLMessage)
+	for _, msg := range g.wsdl.Messages {
+		name := xml.Name{Space: g.wsdl.TargetNamespace, Local: msg.Name}
+		if _, ok := messages[name]; !ok {
+			messages[name] = msg
+		}
+		if _, ok := localMessages[msg.Name]; !ok {
+			localMessages[msg.Name] = msg
+		}
+	}
+	// References are resolved by qualified name. Those that do not resolve,
+	// through an undeclared prefix or a chameleon include for instance, fall
+	// back to every declaration of their local name, like the generator
+	// does.
+	elements := make(map[xml.Name][]declaration)
+	localElements := make(map[string][]declaration)
+	foldedElements := make(map[string][]declaration)
+	types := make(map[xml.Name][]declaration)
+	localTypes := make(map[string][]declaration)
+	for _, schema := range g.wsdl.Types.Schemas {
+		declare := func(qualified map[xml.Name][]declaration, local map[string][]declaration, name string, node interface{}) {
+			d := declaration{schema, node}
+			qname := xml.Name{Space: schema.TargetNamespace, Local: name}
+			qualified[qname] = append(qualified[qname], d)
+			local[name] = append(local[name], d)
+		}
+		for _, el := range schema.Elements {
+			declare(elements, localElements, el.Name, el)
+			folded := strings.ToLower(el.Name)
+			foldedElements[folded] = append(foldedElements[folded], declaration{schema, el})
+		}
+		for _, ct := range schema.ComplexTypes {
+			declare(types, localTypes, ct.Name, ct)
+		}
+		for _, st := range schema.SimpleType {
+			declare(types, localTypes, st.Name, st)
+		}
+	}
+	lookup := func(qualified map[xml.Name][]declaration, local map[string][]declaration, xmlns map[string]string, name string) []declaration {
+		if name == "" {
+			return nil
+		}
+		if decls, ok := qualified[xmlName(xmlns, name)]; ok {
+			return decls
+		}
+		return local[stripns(name)]
+	}
+
+	// Walk everything reachable from the operations, marking the global
+	// declarations met on the way. Nodes are walked along with the schema
+	// declaring them, whose prefixes their references use.
+	reached := make(map[interface{}]bool)
+	var (
+		element     func(schema *XSDSchema, el *XSDElement)
+		complexType func(schema *XSDSchema, ct *XSDComplexType)
+		simpleType  func(schema *XSDSchema, st *XSDSimpleType)
+	)
+	visit := func(decls []declaration) {
+		for _, d := range decls {
+			switch node := d.node.(type) {
+			case *XSDElement:
+				element(d.schema, node)
+			case *XSDComplexType:
+				complexType(d.schema, node)
+			case *XSDSimpleType:
+				simpleType(d.schema, node)
+			}
+		}
+	}
+	typeRef := func(xmlns map[string]string, name string) {
+		visit(lookup(types, localTypes, xmlns, name))
+	}
+	elementRef := func(xmlns map[string]string, name string) {
+		visit(lookup(elements, localElements, xmlns, name))
+	}
+	attribute := func(schema *XSDSchema, attr *XSDAttribute) {
+		typeRef(schema.Xmlns, attr.Type)
+		if attr.SimpleType != nil {
+			simpleType(schema, attr.SimpleType)
+		}
+	}
+	elementsOf := func(schema *XSDSchema, lists ...[]*XSDElement) {
+		for _, list := range lists {
+			for _, el := range list {
+				element(schema, el)
+			}
+		}
+	}
+	element = func(schema *XSDSchema, el *XSDElement) {
+		if reached[el] {
+			return
+		}
+		reached[el] = true
+		elementRef(schema.Xmlns, el.Ref)
+		typeRef(schema.Xmlns, el.Type)
+		if el.ComplexType != nil {
+			complexType(schema, el.ComplexType)
+		}
+		if el.SimpleType != nil {
+			simpleType(schema, el.SimpleType)
+		}
+	}
+	complexType = func(schema *XSDSchema, ct *XSDComplexType) {
+		if reached[ct] {
+			return
+		}
+		reached[ct] = true
+		elementsOf(schema, ct.Sequence, ct.Choice, ct.SequenceChoice, ct.All)
+		for _, attr := range ct.Attributes {
+			attribute(schema, attr)
+		}
+		for _, ext := range []XSDExtension{ct.ComplexContent.Extension, ct.SimpleContent.Extension} {
+			typeRef(schema.Xmlns, ext.Base)
+			elementsOf(schema, ext.Sequence, ext.Choice, ext.SequenceChoice)
+			for _, attr := range ext.Attributes {
+				attribute(schema, attr)
+			}
+		}
+	}
+	simpleType = func(schema *XSDSchema, st *XSDSimpleType) {
+		if reached[st] {
+			return
+		}
+		reached[st] = true
+		typeRef(schema.Xmlns, st.Restriction.Base)
+		typeRef(schema.Xmlns, st.List.ItemType)
+		if st.List.SimpleType != nil {
+			simpleType(schema, st.List.SimpleType)
+		}
+		for _, member := range strings.Fields(st.Union.MemberTypes) {
+			typeRef(schema.Xmlns, member)
+		}
+		for _, member := range st.Union.SimpleType {
+			simpleType(schema, member)
+		}
+	}
+	message := func(name string, part string) {
+		msg := messages[xmlName(g.wsdl.Xmlns, name)]
+		if msg == nil {
+			msg = localMessages[stripns(name)]
+		}
+		if msg == nil {
+			return
+		}
+		for _, p := range msg.Parts {
+			if part == "" || p.Name == part {
+				// Message parts also match elements regardless of case,
+				// like the generator does.
+				if decls := lookup(elements, localElements, g.wsdl.Xmlns, p.Element); decls != nil || p.Element == "" {
+					visit(decls)
+				} else {
+					visit(foldedElements[strings.ToLower(stripns(p.Element))])
+				}
+				typeRef(g.wsdl.Xmlns, p.Type)
+			}
+		}
+	}
+
+	for _, pt := range g.wsdl.PortTypes {
+		for _, op := range pt.Operations {
+			message(op.Input.Message, "")
+			message(op.Output.Message, "")
+			for _, fault := range op.Faults {
+				message(fault.Message, "")
+			}
+		}
+	}
+	for _, binding := range g.wsdl.Binding {
+		for _, op := range binding.Operations {
+			for _, header := range op.Input.SOAPHeader {
+				message(header.Message, header.Part)
+			}
+			for _, header := range op.Output.SOAPHeader {
+				message(header.Message, header.Part)
+			}
+		}
+	}
+
+	// Types derived from reached ones may be sent in their place, with
+	// xsi:type.
+	derivesFromReached := func(schema *XSDSchema, ct *XSDComplexType) bool {
+		for _, base := range []string{ct.ComplexContent.Extension.Base, ct.SimpleContent.Extension.Base} {
+			for _, d := range lookup(types, localTypes, schema.Xmlns, base) {
+				if _, ok := d.node.(*XSDComplexType); ok && reached[d.node] {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	for derived := true; derived; {
+		derived = false
+		for _, schema := range g.wsdl.Types.Schemas {
+			for _, ct := range schema.ComplexTypes {
+				if !reached[ct] && derivesFromReached(schema, ct) {
+					complexType(schema, ct)
+					derived = true
+				}
+			}
+		}
+	}
+
+	for _, schema := range g.wsdl.Types.Schemas {
+		kept := schema.Elements[:0]
+		for _, el := range schema.Elements {
+			if reached[el] {
+				kept = append(kept, el)
+			}
+		}
+		schema.Elements = kept
+
+		keptComplex := schema.ComplexTypes[:0]
+		for _, ct := range schema.ComplexTypes {
+			if reached[ct] {
+				keptComplex = append(keptComplex, ct)
+			}
+		}
+		schema.ComplexTypes = keptComplex
+
+		keptSimple := schema.SimpleType[:0]
+		for _, st := range schema.SimpleType {
+			if reached[st] {
+				keptSimple = append(keptSimple, st)
+			}
+		}
+		schema.SimpleType = keptSimple
+	}
+}
@@ -0,0 +1,255 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const operationsWSDL = `<?xml version="1.0" encoding="UTF-8"?>
+<definitions name="Shop" targetNamespace="urn:shop:wsdl"
+  xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"
+  xmlns:tns="urn:shop:wsdl"
+  xmlns:s="urn:shop">
+  <types>
+    <xs:schema targetNamespace="urn:shop" elementFormDefault="qualified"
+      xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:s="urn:shop">
+      <xs:simpleType name="Currency">
+        <xs:restriction base="xs:string"/>
+      </xs:simpleType>
+      <xs:complexType name="Price">
+        <xs:sequence><xs:element name="amount" type="xs:decimal"/></xs:sequence>
+        <xs:attribute name="currency" type="s:Currency"/>
+      </xs:complexType>
+      <xs:complexType name="Discount">
+        <xs:complexContent>
+          <xs:extension base="s:Price">
+            <xs:sequence><xs:element name="code" type="xs:string"/></xs:sequence>
+          </xs:extension>
+        </xs:complexContent>
+      </xs:complexType>
+      <xs:complexType name="Stock">
+        <xs:sequence><xs:element name="count" type="xs:int"/></xs:sequence>
+      </xs:complexType>
+      <xs:element name="GetPrice" type="xs:string"/>
+      <xs:element name="GetPriceResponse" type="s:Price"/>
+      <xs:element name="GetStock" type="xs:string"/>
+      <xs:element name="GetStockResponse" type="s:Stock"/>
+      <xs:element name="Session">
+        <xs:complexType><xs:sequence><xs:element name="id" type="xs:string"/></xs:sequence></xs:complexType>
+      </xs:element>
+      <xs:element name="ShopFault">
+        <xs:complexType><xs:sequence><xs:element name="reason" type="xs:string"/></xs:sequence></xs:complexType>
+      </xs:element>
+    </xs:schema>
+  </types>
+  <message name="GetPriceRequest"><part name="body" element="s:GetPrice"/></message>
+  <message name="SessionHeader"><part name="session" element="s:Session"/></message>
+  <message name="GetPriceResponse"><part name="body" element="s:GetPriceResponse"/></message>
+  <message name="GetStockRequest"><part name="body" element="s:GetStock"/></message>
+  <message name="GetStockResponse"><part name="body" element="s:GetStockResponse"/></message>
+  <message name="ShopFault"><part name="fault" element="s:ShopFault"/></message>
+  <portType name="PricesPortType">
+    <operation name="GetPrice">
+      <input message="tns:GetPriceRequest"/>
+      <output message="tns:GetPriceResponse"/>
+      <fault name="ShopFault" message="tns:ShopFault"/>
+    </operation>
+  </portType>
+  <portType name="StockPortType">
+    <operation name="GetStock">
+      <input message="tns:GetStockRequest"/>
+      <output message="tns:GetStockResponse"/>
+    </operation>
+  </portType>
+  <binding name="PricesBinding" type="tns:PricesPortType">
+    <soap:binding style="document" transport="http://schemas.xmlsoap.org/soap/http"/>
+    <operation name="GetPrice">
+      <soap:operation soapAction="urn:GetPrice"/>
+      <input><soap:body use="literal"/><soap:header message="tns:SessionHeader" part="session" use="literal"/></input>
+      <output><soap:body use="literal"/></output>
+      <fault name="ShopFault"><soap:fault name="ShopFault" use="literal"/></fault>
+    </operation>
+  </binding>
+  <binding name="StockBinding" type="tns:StockPortType">
+    <soap:binding style="document" transport="http://schemas.xmlsoap.org/soap/http"/>
+    <operation name="GetStock">
+      <soap:operation soapAction="urn:GetStock"/>
+      <input><soap:body use="literal"/></input>
+      <output><soap:body use="literal"/></output>
+    </operation>
+  </binding>
+</definitions>`
+
+func TestOperations(t *testing.T) {
+	for _, include := range []string{"GetP*", "PricesPortType.GetPrice", "/^Get(Price|Quote)$/"} {
+		g, err := New("shop.wsdl", WithLoader("", MapLoader{"shop.wsdl": []byte(operationsWSDL)}),
+			WithOperations(Operations{Include: []string{include}}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := g.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		code := result.Files[0].Content
+		for _, want := range []string{
+			"GetPrice(request *string) (*Price, error)",
+			"type Price struct",
+			"type Currency string",
+			"type Discount struct",
+			"type Session struct",
+			"type ShopFault struct",
+		} {
+			if !bytes.Contains(code, []byte(want)) {
+				t.Errorf("%s: generated code does not contain %q:\n%s", include, want, code)
+			}
+		}
+		for _, unwanted := range []string{"GetStock", "type Stock ", "StockPortType"} {
+			if bytes.Contains(code, []byte(unwanted)) {
+				t.Errorf("%s: generated code contains %q", include, unwanted)
+			}
+		}
+	}
+
+	g, err := New("shop.wsdl", WithLoader("", MapLoader{"shop.wsdl": []byte(operationsWSDL)}),
+		WithOperations(Operations{Exclude: []string{"GetPrice"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := g.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := result.Files[0].Content
+	if !bytes.Contains(code, []byte("type Stock struct")) || bytes.Contains(code, []byte("type Price struct")) {
+		t.Errorf("excluding GetPrice generated:\n%s", code)
+	}
+
+	_, err = New("shop.wsdl", WithOperations(Operations{Include: []string{"/(/"}}))
+	if err == nil || !strings.Contains(err.Error(), "operation pattern /(/") {
+		t.Errorf("got error %v, want one about the pattern", err)
+	}
+}
+
+const qualifiedOperationsWSDL = `<?xml version="1.0" encoding="UTF-8"?>
+<definitions name="Shop" targetNamespace="urn:shop:wsdl"
+  xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"
+  xmlns:tns="urn:shop:wsdl"
+  xmlns:s="urn:shop">
+  <types>
+    <xs:schema targetNamespace="urn:shop" elementFormDefault="qualified"
+      xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:s="urn:shop" xmlns:w="urn:warehouse">
+      <xs:complexType name="Price">
+        <xs:sequence><xs:element name="amount" type="xs:decimal"/></xs:sequence>
+      </xs:complexType>
+      <xs:complexType name="Stock">
+        <xs:sequence><xs:element name="cost" type="w:Price"/></xs:sequence>
+      </xs:complexType>
+      <xs:element name="GetPrice" type="xs:string"/>
+      <xs:element name="GetPriceResponse" type="s:Price"/>
+      <xs:element name="GetStock" type="xs:string"/>
+      <xs:element name="GetStockResponse" type="s:Stock"/>
+    </xs:schema>
+    <xs:schema targetNamespace="urn:warehouse" elementFormDefault="qualified"
+      xmlns:xs="http://www.w3.org/2001/XMLSchema">
+      <xs:complexType name="Price">
+        <xs:sequence><xs:element name="warehouse" type="xs:string"/></xs:sequence>
+      </xs:complexType>
+      <xs:element name="GetPriceResponse" type="xs:int"/>
+    </xs:schema>
+  </types>
+  <message name="GetPriceRequest"><part name="body" element="s:GetPrice"/></message>
+  <message name="GetPriceResponse"><part name="body" element="s:GetPriceResponse"/></message>
+  <message name="GetStockRequest"><part name="body" element="s:GetStock"/></message>
+  <message name="GetStockResponse"><part name="body" element="s:GetStockResponse"/></message>
+  <portType name="ShopPortType">
+    <operation name="GetPrice">
+      <input message="tns:GetPriceRequest"/>
+      <output message="tns:GetPriceResponse"/>
+    </operation>
+    <operation name="GetStock">
+      <input message="tns:GetStockRequest"/>
+      <output message="tns:GetStockResponse"/>
+    </operation>
+  </portType>
+</definitions>`
+
+func TestOperationsQualifiedTypes(t *testing.T) {
+	g, err := New("shop.wsdl", WithLoader("", MapLoader{"shop.wsdl": []byte(qualifiedOperationsWSDL)}),
+		WithOperations(Operations{Include: []string{"GetPrice"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := g.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The types and elements of the same name in urn:warehouse are not
+	// needed by GetPrice.
+	code := result.Files[0].Content
+	if !bytes.Contains(code, []byte("Amount float64")) {
+		t.Errorf("generated code does not contain the Price of urn:shop:\n%s", code)
+	}
+	for _, unwanted := range []string{"Warehouse", "int32"} {
+		if bytes.Contains(code, []byte(unwanted)) {
+			t.Errorf("generated code contains %q:\n%s", unwanted, code)
+		}
+	}
+}
+
+const partCaseWSDL = `<?xml version="1.0" encoding="UTF-8"?>
+<definitions name="Shop" targetNamespace="urn:shop:wsdl"
+  xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:tns="urn:shop:wsdl"
+  xmlns:s="urn:shop">
+  <types>
+    <xs:schema targetNamespace="urn:shop" elementFormDefault="qualified"
+      xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:s="urn:shop">
+      <xs:complexType name="Price">
+        <xs:sequence><xs:element name="amount" type="xs:decimal"/></xs:sequence>
+      </xs:complexType>
+      <xs:element name="GetPrice" type="xs:string"/>
+      <xs:element name="GetPriceResponse" type="s:Price"/>
+    </xs:schema>
+  </types>
+  <message name="GetPriceRequest"><part name="body" element="s:getPrice"/></message>
+  <message name="GetPriceResponse"><part name="body" element="s:getpriceresponse"/></message>
+  <portType name="ShopPortType">
+    <operation name="GetPrice">
+      <input message="tns:GetPriceRequest"/>
+      <output message="tns:GetPriceResponse"/>
+    </operation>
+  </portType>
+</definitions>`
+
+func TestOperationsPartCase(t *testing.T) {
+	g, err := New("shop.wsdl", WithLoader("", MapLoader{"shop.wsdl": []byte(partCaseWSDL)}),
+		WithOperations(Operations{Include: []string{"GetPrice"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := g.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Message parts match the elements whose names only differ by case.
+	code := result.Files[0].Content
+	for _, expected := range []string{
+		"GetPrice(request *string) (*Price, error)",
+		"Amount float64",
+	} {
+		if !bytes.Contains(code, []byte(expected)) {
+			t.Errorf("generated code does not contain %q:\n%s", expected, code)
+		}
+	}
+}
@@ -6,15 +6,17 @@ package gowsdl
 
 var opsTmpl = `
 {{range .}}
-	{{$privateType := .Name | makePrivate}}
-	{{$exportType := .Name | makePublic}}
+	{{$portType := .Name}}
+	{{$names := portTypeNames .}}
+	{{$privateType := $names.Impl}}
+	{{$exportType := $names.Interface}}
 
 	type {{$exportType}} interface {
 		{{range .Operations}}
 			{{$faults := len .Faults}}
-			{{$soapAction := findSOAPAction .Name $privateType}}
-			{{$requestType := findType .Input.Message | replaceReservedWords | makePublic}}
-			{{$responseType := findType .Output.Message | replaceReservedWords | makePublic}}
+			{{$soapAction := findSOAPAction .Name $portType}}
+			{{$requestType := findType .Input.Message}}
+			{{$responseType := findType .Output.Message}}
 
 			{{/*if ne $soapAction ""*/}}
 			{{if gt $faults 0}}
@@ -22,9 +24,9 @@ var opsTmpl = `
 			// {{range .Faults}}
 			//   - {{.Name}} {{.Doc}}{{end}}{{end}}
 			{{if ne .Doc ""}}/* {{.Doc}} */{{end}}
-			{{makePublic .Name | replaceReservedWords}} ({{if ne $requestType ""}}request *{{$requestType}}{{end}}) ({{if ne $responseType ""}}*{{$responseType}}, {{end}}error)
+			{{operationName .}} ({{if ne $requestType ""}}request *{{$requestType}}{{end}}) ({{if ne $responseType ""}}*{{$responseType}}, {{end}}error)
 			{{/*end*/}}
-			{{makePublic .Name | replaceReservedWords}}Context (ctx context.Context, {{if ne $requestType ""}}request *{{$requestType}}{{end}}) ({{if ne $responseType ""}}*{{$responseType}}, {{end}}error)
+			{{operationName .}}Context (ctx context.Context, {{if ne $requestType ""}}request *{{$requestType}}{{end}}) ({{if ne $responseType ""}}*{{$responseType}}, {{end}}error)
 			{{/*end*/}}
 		{{end}}
 	}
@@ -33,17 +35,17 @@ var opsTmpl = `
 		client *soap.Client
 	}
 
-	func New{{$exportType}}(client *soap.Client) {{$exportType}} {
+	func {{$names.Constructor}}(client *soap.Client) {{$exportType}} {
 		return &{{$privateType}}{
 			client: client,
 		}
 	}
 
 	{{range .Operations}}
-		{{$requestType := findType .Input.Message | replaceReservedWords | makePublic}}
-		{{$soapAction := findSOAPAction .Name $privateType}}
-		{{$responseType := findType .Output.Message | replaceReservedWords | makePublic}}
-		func (service *{{$privateType}}) {{makePublic .Name | replaceReservedWords}}Context (ctx context.Context, {{if ne $requestType ""}}request *{{$requestType}}{{end}}) ({{if ne $responseType ""}}*{{$responseType}}, {{end}}error) {
+		{{$requestType := findType .Input.Message}}
+		{{$soapAction := findSOAPAction .Name $portType}}
+		{{$responseType := findType .Output.Message}}
+		func (service *{{$privateType}}) {{operationName .}}Context (ctx context.Context, {{if ne $requestType ""}}request *{{$requestType}}{{end}}) ({{if ne $responseType ""}}*{{$responseType}}, {{end}}error) {
 			{{if ne $responseType ""}}response := new({{$responseType}}){{end}}
 			err := service.client.CallContext(ctx, "{{if ne $soapAction ""}}{{$soapAction}}{{else}}''{{end}}", {{if ne $requestType ""}}request{{else}}nil{{end}}, {{if ne $responseType ""}}response{{else}}struct{}{}{{end}})
 			if err != nil {
@@ -53,8 +55,8 @@ var opsTmpl = `
 			return {{if ne $responseType ""}}response, {{end}}nil
 		}
 
-		func (service *{{$privateType}}) {{makePublic .Name | replaceReservedWords}} ({{if ne $requestType ""}}request *{{$requestType}}{{end}}) ({{if ne $responseType ""}}*{{$responseType}}, {{end}}error) {
-			return service.{{makePublic .Name | replaceReservedWords}}Context(
+		func (service *{{$privateType}}) {{operationName .}} ({{if ne $requestType ""}}request *{{$requestType}}{{end}}) ({{if ne $responseType ""}}*{{$responseType}}, {{end}}error) {
+			return service.{{operationName .}}Context(
 				context.Background(),
 				{{if ne $requestType ""}}request,{{end}}
 			)
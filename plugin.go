@@ -0,0 +1,113 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// PluginPrefix prefixes the names of the executables of generator plugins
+// given by name, so that plugin ts runs gowsdl-gen-ts.
+const PluginPrefix = "gowsdl-gen-"
+
+// PluginVersion is the version of the plugin protocol, bumped on changes
+// that are not backward compatible.
+const PluginVersion = 1
+
+// PluginRequest is what generator plugins read, in JSON, on their standard
+// input.
+type PluginRequest struct {
+	Version int `json:"version"`
+	// Parameter is passed as is from the user to the plugin.
+	Parameter string `json:"parameter,omitempty"`
+	Model     *Model `json:"model"`
+}
+
+// PluginResponse is what generator plugins write, in JSON, on their standard
+// output.
+type PluginResponse struct {
+	Files []PluginFile `json:"files,omitempty"`
+	// Error reports why the plugin could not generate code.
+	Error string `json:"error,omitempty"`
+}
+
+// PluginFile is a file generated by a plugin.
+type PluginFile struct {
+	// Name is a slash separated path, relative to the output directory.
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// RunPlugin generates code with an external generator, rather than Go code.
+// plugin is the path of its executable, or a name looked up in PATH with
+// PluginPrefix prepended. The plugin reads a PluginRequest, holding the
+// Model of the WSDL and parameter, on its standard input, and writes a
+// PluginResponse on its standard output.
+func (g *GoWSDL) RunPlugin(plugin, parameter string) (*Result, error) {
+	model, err := g.Model()
+	if err != nil {
+		return nil, err
+	}
+	if err := g.diagnosticsError(); err != nil {
+		return nil, err
+	}
+
+	request, err := json.Marshal(&PluginRequest{
+		Version:   PluginVersion,
+		Parameter: parameter,
+		Model:     model,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.ContainsAny(plugin, `/\`) {
+		plugin = PluginPrefix + plugin
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(plugin)
+	cmd.Stdin = bytes.NewReader(request)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("plugin %s: %v: %s", plugin, err, msg)
+		}
+		return nil, fmt.Errorf("plugin %s: %v", plugin, err)
+	}
+
+	var response PluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return nil, fmt.Errorf("plugin %s: invalid response: %v", plugin, err)
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("plugin %s: %s", plugin, response.Error)
+	}
+
+	result := &Result{}
+	for _, f := range response.Files {
+		if err := checkPluginFile(f.Name); err != nil {
+			return nil, fmt.Errorf("plugin %s: %v", plugin, err)
+		}
+		result.Files = append(result.Files, File{Name: f.Name, Content: []byte(f.Content)})
+	}
+	return result, nil
+}
+
+// checkPluginFile checks that a plugin file stays within the output
+// directory.
+func checkPluginFile(name string) error {
+	clean := path.Clean(name)
+	if name == "" || strings.Contains(name, `\`) || path.IsAbs(clean) ||
+		clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("file name %q is not a relative path within the output directory", name)
+	}
+	return nil
+}
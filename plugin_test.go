@@ -0,0 +1,79 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRunPlugin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+	dir, err := ioutil.TempDir("", "gowsdl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	request := filepath.Join(dir, "request.json")
+	plugin := func(name, response string) string {
+		script := "#!/bin/sh\ncat > " + request + "\nprintf '%s' '" + response + "'\n"
+		file := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(file, []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+
+	g, err := New("orders.wsdl", WithLoader("", MapLoader{"orders.wsdl": []byte(modelWSDL)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := g.RunPlugin(plugin("ts", `{"files": [{"name": "src/orders.ts", "content": "export {}\n"}]}`), "esm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Files) != 1 || result.Files[0].Name != "src/orders.ts" || string(result.Files[0].Content) != "export {}\n" {
+		t.Errorf("got files %+v", result.Files)
+	}
+
+	data, err := ioutil.ReadFile(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req PluginRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Version != PluginVersion || req.Parameter != "esm" || req.Model == nil || req.Model.Name != "Orders" {
+		t.Errorf("plugin got request version %d, parameter %q, model %+v", req.Version, req.Parameter, req.Model)
+	}
+
+	for name, test := range map[string]struct {
+		response string
+		err      string
+	}{
+		"failing":  {`{"error": "unsupported binding"}`, "unsupported binding"},
+		"escaping": {`{"files": [{"name": "../orders.ts", "content": ""}]}`, "not a relative path"},
+		"absolute": {`{"files": [{"name": "/tmp/orders.ts", "content": ""}]}`, "not a relative path"},
+		"invalid":  {`files`, "invalid response"},
+	} {
+		g, err := New("orders.wsdl", WithLoader("", MapLoader{"orders.wsdl": []byte(modelWSDL)}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = g.RunPlugin(plugin(name, test.response), "")
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got error %v, want %q", name, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+// adoptNamespace makes schema, included without a target namespace of its
+// own, a chameleon of the including schema: its components and the
+// unqualified references between them move to the including namespace.
+func adoptNamespace(schema *XSDSchema, including *XSDSchema) {
+	if schema.TargetNamespace != "" || including.TargetNamespace == "" {
+		return
+	}
+	schema.TargetNamespace = including.TargetNamespace
+	if _, ok := schema.Xmlns[""]; !ok {
+		schema.Xmlns[""] = including.TargetNamespace
+	}
+	if schema.ElementFormDefault == "" {
+		schema.ElementFormDefault = including.ElementFormDefault
+	}
+}
+
+// redefine replaces the components of schemas, a redefined or overridden
+// schema along with the ones it includes, by the components of r having the
+// same name. Components of xs:redefine that derive from themselves are
+// merged with the original ones; xs:override replaces them as they are.
+// Components without an original one are added, and notFound is called
+// with their kind and name. xs:redefine cannot redefine elements and
+// attributes, which are skipped with a call to invalid.
+func redefine(schemas []*XSDSchema, r *XSDRedefine, override bool, notFound, invalid func(component, name string)) {
+	for _, ct := range r.ComplexTypes {
+		schema, i := findComplexType(schemas, ct.Name)
+		if schema == nil {
+			notFound("complexType", ct.Name)
+			schemas[0].ComplexTypes = append(schemas[0].ComplexTypes, ct)
+			continue
+		}
+		if !override && stripns(ct.ComplexContent.Extension.Base) == ct.Name {
+			ct = extendComplexType(schema.ComplexTypes[i], ct)
+		} else if !override && stripns(ct.ComplexContent.Restriction.Base) == ct.Name {
+			ct = restrictComplexType(schema.ComplexTypes[i], ct)
+		}
+		schema.ComplexTypes[i] = ct
+	}
+
+	for _, st := range r.SimpleTypes {
+		schema, i := findSimpleType(schemas, st.Name)
+		if schema == nil {
+			notFound("simpleType", st.Name)
+			schemas[0].SimpleType = append(schemas[0].SimpleType, st)
+			continue
+		}
+		if !override && stripns(st.Restriction.Base) == st.Name {
+			orig := schema.SimpleType[i]
+			st.Restriction.Base = orig.Restriction.Base
+			st.List = orig.List
+			st.Union = orig.Union
+		}
+		schema.SimpleType[i] = st
+	}
+
+	for _, group := range r.Groups {
+		schema, i := findGroup(schemas, group.Name)
+		if schema == nil {
+			notFound("group", group.Name)
+			schemas[0].Groups = append(schemas[0].Groups, group)
+			continue
+		}
+		if !override {
+			group = extendGroup(schema.Groups[i], group)
+		}
+		schema.Groups[i] = group
+	}
+
+	for _, el := range r.Elements {
+		if !override {
+			invalid("element", el.Name)
+			continue
+		}
+		if !replaceElement(schemas, el) {
+			schemas[0].Elements = append(schemas[0].Elements, el)
+		}
+	}
+
+	for _, attr := range r.Attributes {
+		if !override {
+			invalid("attribute", attr.Name)
+			continue
+		}
+		if !replaceAttribute(schemas, attr) {
+			schemas[0].Attributes = append(schemas[0].Attributes, attr)
+		}
+	}
+}
+
+// extendComplexType returns the redefinition ct of orig, which extends the
+// original definition, with the content of both.
+func extendComplexType(orig, ct *XSDComplexType) *XSDComplexType {
+	ext := ct.ComplexContent.Extension
+	merged := *orig
+	if merged.ComplexContent.Extension.Base != "" {
+		target := &merged.ComplexContent.Extension
+		target.GroupPositions = appendPositions(target.GroupPositions, len(target.Sequence), ext.GroupPositions)
+		target.ChoiceGroupPositions = appendPositions(target.ChoiceGroupPositions, len(target.Choice), ext.ChoiceGroupPositions)
+		target.Sequence = append(append([]*XSDElement{}, target.Sequence...), ext.Sequence...)
+		target.Choice = append(append([]*XSDElement{}, target.Choice...), ext.Choice...)
+		target.SequenceChoice = append(append([]*XSDElement{}, target.SequenceChoice...), ext.SequenceChoice...)
+		target.Attributes = append(append([]*XSDAttribute{}, target.Attributes...), ext.Attributes...)
+		target.Groups = append(append([]*XSDGroup{}, target.Groups...), ext.Groups...)
+		target.ChoiceGroups = append(append([]*XSDGroup{}, target.ChoiceGroups...), ext.ChoiceGroups...)
+	} else {
+		merged.GroupPositions = appendPositions(merged.GroupPositions, len(merged.Sequence), ext.GroupPositions)
+		merged.ChoiceGroupPositions = appendPositions(merged.ChoiceGroupPositions, len(merged.Choice), ext.ChoiceGroupPositions)
+		merged.Sequence = append(append([]*XSDElement{}, merged.Sequence...), ext.Sequence...)
+		merged.Choice = append(append([]*XSDElement{}, merged.Choice...), ext.Choice...)
+		merged.SequenceChoice = append(append([]*XSDElement{}, merged.SequenceChoice...), ext.SequenceChoice...)
+		merged.Attributes = append(append([]*XSDAttribute{}, merged.Attributes...), ext.Attributes...)
+		merged.Groups = append(append([]*XSDGroup{}, merged.Groups...), ext.Groups...)
+		merged.ChoiceGroups = append(append([]*XSDGroup{}, merged.ChoiceGroups...), ext.ChoiceGroups...)
+	}
+	return &merged
+}
+
+// restrictComplexType returns the redefinition ct of orig, which restricts
+// the original definition. A restriction declares the whole content of the
+// type, base content included, so it replaces the content of orig; the
+// attributes of orig are kept unless declared again or prohibited.
+func restrictComplexType(orig, ct *XSDComplexType) *XSDComplexType {
+	res := ct.ComplexContent.Restriction
+	attrs := orig.Attributes
+	if orig.ComplexContent.Extension.Base != "" {
+		attrs = orig.ComplexContent.Extension.Attributes
+	}
+
+	merged := *orig
+	merged.ComplexContent = XSDComplexContent{}
+	merged.Sequence = res.Sequence
+	merged.Choice = res.Choice
+	merged.SequenceChoice = res.SequenceChoice
+	merged.All = nil
+	merged.Groups = res.Groups
+	merged.ChoiceGroups = res.ChoiceGroups
+	merged.SequenceOccurs = res.SequenceOccurs
+	merged.ChoiceOccurs = res.ChoiceOccurs
+	merged.SequenceChoiceOccurs = res.SequenceChoiceOccurs
+	merged.GroupPositions = res.GroupPositions
+	merged.ChoiceGroupPositions = res.ChoiceGroupPositions
+
+	restricted := make(map[string]*XSDAttribute)
+	for _, attr := range res.Attributes {
+		restricted[attributeName(attr)] = attr
+	}
+	merged.Attributes = nil
+	for _, attr := range attrs {
+		if r, ok := restricted[attributeName(attr)]; ok {
+			attr = r
+		}
+		if attr.Use != "prohibited" {
+			merged.Attributes = append(merged.Attributes, attr)
+		}
+	}
+	return &merged
+}
+
+func attributeName(attr *XSDAttribute) string {
+	if attr.Ref != "" {
+		return stripns(attr.Ref)
+	}
+	return attr.Name
+}
+
+// extendGroup returns the redefinition group of orig, in which references
+// to the group itself stand for the content of the original definition.
+func extendGroup(orig, group *XSDGroup) *XSDGroup {
+	var refs []*XSDGroup
+	var positions []int
+	self := false
+	for i, ref := range group.Groups {
+		if stripns(ref.Ref) == group.Name {
+			self = true
+			continue
+		}
+		refs = append(refs, ref)
+		if i < len(group.GroupPositions) {
+			positions = append(positions, group.GroupPositions[i])
+		}
+	}
+	if !self {
+		return group
+	}
+
+	merged := *group
+	merged.GroupPositions = appendPositions(orig.GroupPositions, len(orig.Sequence), positions)
+	merged.ChoiceGroupPositions = appendPositions(orig.ChoiceGroupPositions, len(orig.Choice), group.ChoiceGroupPositions)
+	merged.Sequence = append(append([]XSDElement{}, orig.Sequence...), group.Sequence...)
+	merged.Choice = append(append([]XSDElement{}, orig.Choice...), group.Choice...)
+	merged.All = append(append([]XSDElement{}, orig.All...), group.All...)
+	merged.Groups = append(append([]*XSDGroup{}, orig.Groups...), refs...)
+	merged.ChoiceGroups = append(append([]*XSDGroup{}, orig.ChoiceGroups...), group.ChoiceGroups...)
+	return &merged
+}
+
+// appendPositions returns the positions of the group references of a model
+// group holding n elements, followed by those of another model group whose
+// content is appended to it.
+func appendPositions(positions []int, n int, more []int) []int {
+	merged := append([]int{}, positions...)
+	for _, p := range more {
+		merged = append(merged, n+p)
+	}
+	return merged
+}
+
+func findComplexType(schemas []*XSDSchema, name string) (*XSDSchema, int) {
+	for _, schema := range schemas {
+		for i, ct := range schema.ComplexTypes {
+			if ct.Name == name {
+				return schema, i
+			}
+		}
+	}
+	return nil, -1
+}
+
+func findSimpleType(schemas []*XSDSchema, name string) (*XSDSchema, int) {
+	for _, schema := range schemas {
+		for i, st := range schema.SimpleType {
+			if st.Name == name {
+				return schema, i
+			}
+		}
+	}
+	return nil, -1
+}
+
+func findGroup(schemas []*XSDSchema, name string) (*XSDSchema, int) {
+	for _, schema := range schemas {
+		for i, group := range schema.Groups {
+			if group.Name == name {
+				return schema, i
+			}
+		}
+	}
+	return nil, -1
+}
+
+func replaceElement(schemas []*XSDSchema, el *XSDElement) bool {
+	for _, schema := range schemas {
+		for i, orig := range schema.Elements {
+			if orig.Name == el.Name {
+				schema.Elements[i] = el
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func replaceAttribute(schemas []*XSDSchema, attr *XSDAttribute) bool {
+	for _, schema := range schemas {
+		for i, orig := range schema.Attributes {
+			if orig.Name == attr.Name {
+				schema.Attributes[i] = attr
+				return true
+			}
+		}
+	}
+	return false
+}
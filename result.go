@@ -0,0 +1,191 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// File is a file generated from the WSDL.
+type File struct {
+	// Name is the slash separated path of the file, relative to the
+	// directory of the package.
+	Name string
+	// Package is the name of the package the file belongs to, empty for
+	// files generated by plugins.
+	Package string
+	// Content is the source code of the file.
+	Content []byte
+	// Formatted tells whether Content is Go code formatted by gofmt. Code
+	// that fails to format is kept as generated, to find out what is wrong.
+	Formatted bool
+}
+
+// Result is the code generated from the WSDL.
+type Result struct {
+	// Files holds the files of the client, with the types and the
+	// operations, then the server, or the files of the Layout.
+	Files []File
+}
+
+// Overwrite is the policy for the existing files a Result is written over.
+type Overwrite int
+
+const (
+	// OverwriteAll replaces existing files. This is the default.
+	OverwriteAll Overwrite = iota
+
+	// OverwriteNone fails when any of the files exists, before writing
+	// any.
+	OverwriteNone
+
+	// OverwriteGenerated replaces the files generated before, which start
+	// with a "Code generated ... DO NOT EDIT." comment, and fails like
+	// OverwriteNone on the others.
+	OverwriteGenerated
+)
+
+// ParseOverwrite parses the name of an overwrite policy, as used by the
+// gowsdl command: "all", "none" or "generated".
+func ParseOverwrite(policy string) (Overwrite, error) {
+	switch strings.ToLower(policy) {
+	case "", "all":
+		return OverwriteAll, nil
+	case "none":
+		return OverwriteNone, nil
+	case "generated":
+		return OverwriteGenerated, nil
+	}
+	return OverwriteAll, fmt.Errorf("unknown overwrite policy %q", policy)
+}
+
+// generatedComment matches the comment that marks generated files, as defined
+// by https://golang.org/s/generatedcode.
+var generatedComment = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// Write writes the files of the result into dir, the directory of the
+// package, creating it if needed and replacing existing files.
+func (r *Result) Write(dir string) error {
+	return r.WriteFiles(dir, OverwriteAll)
+}
+
+// WriteFiles writes the files of the result into dir, the directory of the
+// package, creating it if needed. Existing files are replaced according to
+// overwrite, which is checked for every file before any is written. Each
+// file is written to a temporary file first, then renamed, so that an
+// interrupted write does not leave a truncated file behind.
+func (r *Result) WriteFiles(dir string, overwrite Overwrite) error {
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if overwrite != OverwriteAll {
+		for _, f := range r.Files {
+			name := filepath.Join(dir, filepath.FromSlash(f.Name))
+			data, err := ioutil.ReadFile(name)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if overwrite == OverwriteNone || !isGenerated(data) {
+				return fmt.Errorf("%s already exists", name)
+			}
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, f := range r.Files {
+		name := filepath.Join(dir, filepath.FromSlash(f.Name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			return err
+		}
+		if err := writeFile(name, f.Content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isGenerated tells whether a file has the comment of generated files
+// before its first non-comment line.
+func isGenerated(data []byte) bool {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if generatedComment.MatchString(line) {
+			return true
+		}
+		if line != "" && !strings.HasPrefix(line, "//") {
+			return false
+		}
+	}
+	return false
+}
+
+// writeFile writes data to name through a temporary file of the same
+// directory.
+func writeFile(name string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// Generate generates the Go code of the client and the server of the WSDL,
+// in the files of the Layout. It fails like Start, or when the generated
+// code does not format, in which case it returns the files along with the
+// error.
+func (g *GoWSDL) Generate() (*Result, error) {
+	code, err := g.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	files := g.joinedFiles(code)
+	if g.layout.Split {
+		if split, err := g.splitFiles(code); err != nil {
+			// The code is kept in one file, to find out what is wrong.
+			g.errorf(construct{}, "Splitting the generated code: %v", err)
+		} else {
+			files = split
+		}
+	}
+
+	result := &Result{}
+	for _, f := range files {
+		if source, err := format.Source(f.Content); err != nil {
+			g.errorf(construct{}, "Formatting %s: %v", f.Name, err)
+		} else {
+			f.Content, f.Formatted = source, true
+		}
+		result.Files = append(result.Files, f)
+	}
+
+	if err := g.diagnosticsError(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
@@ -0,0 +1,118 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	g, err := New("fixtures/stock.wsdl", WithPackage("stock"), WithFileName("client.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := g.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{"client.go", "serverclient.go"}
+	if len(result.Files) != len(names) {
+		t.Fatalf("got %d files, want %d", len(result.Files), len(names))
+	}
+	for i, f := range result.Files {
+		if f.Name != names[i] {
+			t.Errorf("file %d is named %q, want %q", i, f.Name, names[i])
+		}
+		if f.Package != "stock" {
+			t.Errorf("%s: package is %q, want stock", f.Name, f.Package)
+		}
+		if !f.Formatted {
+			t.Errorf("%s is not formatted", f.Name)
+		}
+		if !bytes.Contains(f.Content, []byte("package stock")) {
+			t.Errorf("%s does not declare package stock", f.Name)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "gowsdl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := result.Write(filepath.Join(dir, "stock")); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range result.Files {
+		data, err := ioutil.ReadFile(filepath.Join(dir, "stock", f.Name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, f.Content) {
+			t.Errorf("%s written differs from the result", f.Name)
+		}
+	}
+
+	// The defaults of New match the defaults of the command.
+	g, err = New("fixtures/stock.wsdl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err = g.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := result.Files[0]; f.Name != "myservice.go" || f.Package != "myservice" {
+		t.Errorf("got file %s of package %s, want myservice.go of package myservice", f.Name, f.Package)
+	}
+}
+
+func TestWriteFilesOverwrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gowsdl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	generated := &Result{Files: []File{{Name: "a.go", Content: []byte("// Code generated by gowsdl DO NOT EDIT.\n\npackage a\n")}}}
+	if err := generated.WriteFiles(dir, OverwriteNone); err != nil {
+		t.Fatal(err)
+	}
+	if err := generated.WriteFiles(dir, OverwriteNone); err == nil {
+		t.Error("OverwriteNone replaced an existing file")
+	}
+	if err := generated.WriteFiles(dir, OverwriteGenerated); err != nil {
+		t.Errorf("OverwriteGenerated did not replace a generated file: %v", err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "b.go"), []byte("package a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mixed := &Result{Files: []File{
+		{Name: "a.go", Content: []byte("package a\n")},
+		{Name: "b.go", Content: []byte("package a\n")},
+	}}
+	if err := mixed.WriteFiles(dir, OverwriteGenerated); err == nil {
+		t.Error("OverwriteGenerated replaced a file that is not generated")
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "a.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, generated.Files[0].Content) {
+		t.Error("a file was written before the policy failed on another")
+	}
+	if err := mixed.WriteFiles(dir, OverwriteAll); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := ParseOverwrite("sometimes"); err == nil {
+		t.Error("ParseOverwrite accepted an unknown policy")
+	}
+}
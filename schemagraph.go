@@ -0,0 +1,124 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SchemaGraph records how the schemas of a WSDL were resolved: which
+// schemas each of them imports, includes, redefines or overrides.
+type SchemaGraph struct {
+	// Roots are the schemas embedded in the WSDL.
+	Roots []*SchemaNode
+
+	// Schemas resolved so far, by canonical location and namespace.
+	nodes map[string]*SchemaNode
+}
+
+// SchemaNode is a schema of a SchemaGraph.
+type SchemaNode struct {
+	// Location is the canonical location of the schema, or of the WSDL
+	// for the schemas embedded in it.
+	Location  string
+	Namespace string
+	Refs      []*SchemaRef
+}
+
+// SchemaRef is an import, include, redefine or override of a schema.
+type SchemaRef struct {
+	// Kind is import, include, redefine or override.
+	Kind string
+	// Location is the schemaLocation as written in the referencing schema.
+	Location  string
+	Namespace string
+
+	// Schema is the referenced schema, nil if it was not resolved.
+	Schema *SchemaNode
+	// Cycle is set when the referenced schema was still being resolved,
+	// that is, when it directly or indirectly references this schema.
+	Cycle bool
+	// WellKnown is set for imports of namespaces built into the
+	// generator, which need no schema.
+	WellKnown bool
+	// Unresolved tells why Schema is nil.
+	Unresolved string
+}
+
+func newSchemaGraph() *SchemaGraph {
+	return &SchemaGraph{nodes: make(map[string]*SchemaNode)}
+}
+
+// root returns the first schema embedded in the WSDL with the given target
+// namespace.
+func (sg *SchemaGraph) root(namespace string) *SchemaNode {
+	for _, root := range sg.Roots {
+		if root.Namespace == namespace {
+			return root
+		}
+	}
+	return nil
+}
+
+// schemaKey identifies the schema at the canonical location, in namespace.
+// A chameleon schema, included without a namespace of its own, is a
+// different schema in every namespace it is included in.
+func schemaKey(kind, location, namespace string) string {
+	key := location + " " + namespace
+	if kind == "redefine" || kind == "override" {
+		// Redefined components replace the original ones, so the
+		// schema cannot be shared with plain includes.
+		key = kind + " " + key
+	}
+	return key
+}
+
+// String renders the graph as an indented tree. Schemas reached more than
+// once are only expanded the first time.
+func (sg *SchemaGraph) String() string {
+	var b strings.Builder
+	printed := make(map[*SchemaNode]bool)
+	roots := make(map[*SchemaNode]bool)
+	for _, root := range sg.Roots {
+		roots[root] = true
+	}
+
+	var print func(node *SchemaNode, indent string)
+	print = func(node *SchemaNode, indent string) {
+		printed[node] = true
+		for _, ref := range node.Refs {
+			fmt.Fprintf(&b, "%s%s %s", indent, ref.Kind, ref.Location)
+			if ref.Namespace != "" {
+				fmt.Fprintf(&b, " (%s)", ref.Namespace)
+			}
+			switch {
+			case ref.WellKnown:
+				fmt.Fprintf(&b, ": well-known namespace\n")
+			case ref.Schema == nil:
+				fmt.Fprintf(&b, ": unresolved, %s\n", ref.Unresolved)
+			case roots[ref.Schema]:
+				fmt.Fprintf(&b, " -> %s: embedded schema\n", ref.Schema.Location)
+			case ref.Cycle:
+				fmt.Fprintf(&b, " -> %s: cycle\n", ref.Schema.Location)
+			case printed[ref.Schema]:
+				fmt.Fprintf(&b, " -> %s: see above\n", ref.Schema.Location)
+			default:
+				fmt.Fprintf(&b, " -> %s\n", ref.Schema.Location)
+				print(ref.Schema, indent+"  ")
+			}
+		}
+	}
+
+	for _, root := range sg.Roots {
+		namespace := root.Namespace
+		if namespace == "" {
+			namespace = "no namespace"
+		}
+		fmt.Fprintf(&b, "%s (%s)\n", root.Location, namespace)
+		print(root, "  ")
+	}
+	return b.String()
+}
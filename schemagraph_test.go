@@ -0,0 +1,133 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const graphWSDL = `<?xml version="1.0" encoding="utf-8"?>
+<wsdl:definitions xmlns:xs="http://www.w3.org/2001/XMLSchema"
+                  xmlns:wsdl="http://schemas.xmlsoap.org/wsdl/"
+                  targetNamespace="urn:root">
+  <wsdl:types>
+    <xs:schema targetNamespace="urn:root">
+      <xs:import namespace="urn:a0" schemaLocation="a0.xsd"/>
+      <xs:import namespace="urn:b0" schemaLocation="b0.xsd"/>
+      <xs:import namespace="urn:cycle1" schemaLocation="cycle1.xsd"/>
+      <xs:import namespace="urn:embedded"/>
+      <xs:import namespace="urn:missing"/>
+    </xs:schema>
+    <xs:schema targetNamespace="urn:embedded"/>
+  </wsdl:types>
+</wsdl:definitions>`
+
+const graphXSD = `<?xml version="1.0" encoding="utf-8"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="%s">%s</xs:schema>`
+
+func TestSchemaGraph(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Two chains of 15 imports: the depth limit applies to each branch,
+	// not to the total number of schemas.
+	const chain = 15
+	for _, prefix := range []string{"a", "b"} {
+		for i := 0; i < chain; i++ {
+			imp := ""
+			if i < chain-1 {
+				imp = fmt.Sprintf(`<xs:import namespace="urn:%s%d" schemaLocation="%s%d.xsd"/>`, prefix, i+1, prefix, i+1)
+			}
+			write(fmt.Sprintf("%s%d.xsd", prefix, i), fmt.Sprintf(graphXSD, fmt.Sprintf("urn:%s%d", prefix, i), imp))
+		}
+	}
+	write("cycle1.xsd", fmt.Sprintf(graphXSD, "urn:cycle1", `<xs:import namespace="urn:cycle2" schemaLocation="./sub/../cycle2.xsd"/>`))
+	write("cycle2.xsd", fmt.Sprintf(graphXSD, "urn:cycle2", `<xs:import namespace="urn:cycle1" schemaLocation="cycle1.xsd"/>`))
+	write("service.wsdl", graphWSDL)
+
+	g, err := NewGoWSDL(filepath.Join(dir, "service.wsdl"), "myservice", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.unmarshal(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 2 embedded, 2 chains and 2 schemas in a cycle.
+	if n := len(g.wsdl.Types.Schemas); n != 2+2*chain+2 {
+		t.Errorf("got %d schemas, want %d", n, 2+2*chain+2)
+	}
+
+	graph := g.SchemaGraph().String()
+	if !strings.Contains(graph, "import cycle1.xsd (urn:cycle1) -> "+filepath.Join(dir, "cycle1.xsd")+": cycle") {
+		t.Errorf("cycle not reported in graph:\n%s", graph)
+	}
+	if !strings.Contains(graph, "import  (urn:missing): unresolved, no schemaLocation") {
+		t.Errorf("missing import not reported in graph:\n%s", graph)
+	}
+
+	diagnostics := g.Diagnostics()
+	want := Diagnostic{
+		Severity: SeverityWarning,
+		Message:  "Don't know where to find XSD for urn:missing",
+		Source:   filepath.Join(dir, "service.wsdl"),
+		Line:     11,
+		Column:   7,
+	}
+	if len(diagnostics) != 1 || diagnostics[0] != want {
+		t.Errorf("got diagnostics %v, want only %v", diagnostics, want)
+	}
+}
+
+func TestUnresolvedImportsOrder(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("service.wsdl", `<?xml version="1.0" encoding="utf-8"?>
+<wsdl:definitions xmlns:xs="http://www.w3.org/2001/XMLSchema"
+                  xmlns:wsdl="http://schemas.xmlsoap.org/wsdl/"
+                  targetNamespace="urn:root">
+  <wsdl:types>
+    <xs:schema targetNamespace="urn:root">
+      <xs:import namespace="urn:x" schemaLocation="x.xsd"/>
+      <xs:import namespace="urn:m2"/>
+      <xs:import namespace="urn:m1"/>
+    </xs:schema>
+  </wsdl:types>
+</wsdl:definitions>`)
+	write("x.xsd", fmt.Sprintf(graphXSD, "urn:x", `<xs:import namespace="urn:m3"/><xs:import namespace="urn:y" schemaLocation="y.xsd"/>`))
+	write("y.xsd", fmt.Sprintf(graphXSD, "urn:y", `<xs:import namespace="urn:m0"/>`))
+
+	// Warnings follow the order the schemas were loaded in, every time.
+	want := []string{"urn:m2", "urn:m1", "urn:m3", "urn:m0"}
+	for i := 0; i < 10; i++ {
+		g, err := NewGoWSDL(filepath.Join(dir, "service.wsdl"), "myservice", false, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.unmarshal(); err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for _, d := range g.Diagnostics() {
+			got = append(got, strings.TrimPrefix(d.Message, "Don't know where to find XSD for "))
+		}
+		if strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Fatalf("got warnings for %v, want %v", got, want)
+		}
+	}
+}
@@ -13,9 +13,11 @@ import (
 	"encoding/xml"
 	"net/http"
 
-	{{/*range .Imports*/}}
-		{{/*.*/}}
-	{{/*end*/}}
+	{{range imports}}
+		{{printf "%q" .}}
+	{{end}}
 )
 
+{{range importedTypes}}var _ {{.}}
+{{end}}
 `
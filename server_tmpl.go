@@ -11,11 +11,8 @@ type SOAPEnvelopeRequest struct {
 
 type SOAPBodyRequest struct {
 	XMLName xml.Name ` + "`" + `xml:"http://schemas.xmlsoap.org/soap/envelope/ Body"` + "`" + `
-	{{range .}}
-		{{range .Operations}}
-				{{$requestType := findType .Input.Message | replaceReservedWords | makePublic}} ` + `
-  				{{$requestType}} *{{$requestType}} ` + "`" + `xml:,omitempty` + "`" + `
-		{{end}}
+	{{range serverOperations}}
+		{{.Field}} *{{.RequestType}} ` + "`" + `xml:,omitempty` + "`" + `
 	{{end}}
 }
 
@@ -50,25 +47,16 @@ type Fault struct { ` + `
 type SOAPBodyResponse struct { ` + `
 	XMLName xml.Name   ` + "`" + `xml:"soap:Body"` + "`" + `
 	Fault   *Fault ` + "`" + `xml:",omitempty"` + "`" + `
-{{range .}}
-	{{range .Operations}}
-		{{$responseType := findType .Output.Message | replaceReservedWords | makePublic}}
-		{{$requestType := findType .Input.Message | replaceReservedWords | makePublic}} ` + `
-			{{$requestType}} *{{$responseType}} ` + "`" + `xml:",omitempty"` + "`" + `
-	{{end}}
+{{range serverOperations}}
+	{{.Field}} *{{.ResponseType}} ` + "`" + `xml:",omitempty"` + "`" + `
 {{end}}
 
 }
 
-{{range .}}
-	{{range .Operations}}
-		{{$responseType := findType .Output.Message | replaceReservedWords | makePublic}}
-		{{$requestType := findType .Input.Message | replaceReservedWords | makePublic}}
-		{{$requestTypeSource := findType .Input.Message | replaceReservedWords }}
-func (service *SOAPBodyRequest) {{$requestType}}Func(request *{{$requestType}}) (*{{$responseType}}, error) {
+{{range serverOperations}}
+func (service *SOAPBodyRequest) {{.Method}}(request *{{.RequestType}}) (*{{.ResponseType}}, error) {
 	return nil, WSDLUndefinedError
 }
-	{{end}}
 {{end}}
 
 
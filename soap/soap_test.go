@@ -852,3 +852,48 @@ func TestHTTPError(t *testing.T) {
 	}
 
 }
+
+type Customer struct {
+	XMLName xml.Name `xml:"http://example.com/service.xsd Customer"`
+
+	Name string `xml:"Name,omitempty"`
+	ID   string `xml:"id,attr,omitempty"`
+
+	UnknownElements []AnyElement `xml:",any"`
+	UnknownAttrs    []AnyAttr    `xml:",any,attr"`
+}
+
+func TestUnknownElementsRoundTrip(t *testing.T) {
+	in := `<Customer xmlns="http://example.com/service.xsd" xmlns:v2="urn:v2" id="1" v2:tier="gold">` +
+		`<Name>Ana</Name><Email kind="work"><Address>ana@example.com</Address></Email></Customer>`
+
+	var c Customer
+	err := xml.Unmarshal([]byte(in), &c)
+	assert.Nil(t, err)
+	assert.Equal(t, "Ana", c.Name)
+	assert.Equal(t, 1, len(c.UnknownElements))
+	assert.Equal(t, "Email", c.UnknownElements[0].XMLName.Local)
+
+	c.Name = "Ana Maria"
+	out, err := xml.Marshal(c)
+	assert.Nil(t, err)
+
+	var again Customer
+	err = xml.Unmarshal(out, &again)
+	assert.Nil(t, err)
+	assert.Equal(t, "Ana Maria", again.Name)
+	assert.Equal(t, 1, len(again.UnknownElements))
+	assert.Equal(t, "<Address>ana@example.com</Address>", again.UnknownElements[0].InnerXML)
+
+	attrValue := func(attrs []AnyAttr, space, local string) string {
+		for _, attr := range attrs {
+			if attr.Name.Space == space && attr.Name.Local == local {
+				return attr.Value
+			}
+		}
+		return ""
+	}
+	assert.Equal(t, "work", attrValue(again.UnknownElements[0].Attrs, "", "kind"))
+	assert.Equal(t, "gold", attrValue(again.UnknownAttrs, "urn:v2", "tier"))
+	assert.True(t, strings.Index(string(out), "<Email") > strings.Index(string(out), "<Name>"))
+}
@@ -0,0 +1,38 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package soap
+
+import (
+	"encoding/xml"
+)
+
+// AnyElement holds a child element that is not described by the WSDL.
+// Generated types use it to keep elements added by newer versions of a
+// service, so they are written back unchanged on marshal.
+type AnyElement struct {
+	XMLName  xml.Name
+	Attrs    []AnyAttr `xml:",any,attr"`
+	InnerXML string    `xml:",innerxml"`
+}
+
+// AnyAttr holds an attribute that is not described by the WSDL.
+type AnyAttr xml.Attr
+
+// UnmarshalXMLAttr implements xml.UnmarshalerAttr for AnyAttr.
+func (a *AnyAttr) UnmarshalXMLAttr(attr xml.Attr) error {
+	*a = AnyAttr(attr)
+	return nil
+}
+
+// MarshalXMLAttr implements xml.MarshalerAttr for AnyAttr.
+//
+// Namespace declarations are dropped since encoding/xml declares the
+// namespaces it needs on its own when writing the element.
+func (a AnyAttr) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	if a.Name.Space == "xmlns" || (a.Name.Space == "" && a.Name.Local == "xmlns") {
+		return xml.Attr{}, nil
+	}
+	return xml.Attr(a), nil
+}
@@ -0,0 +1,149 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// templateSet is a template code is generated with, along with the templates
+// it defines.
+type templateSet struct {
+	name string
+	text string
+	// funcs returns the functions the templates are parsed with, with the
+	// state of a new rendering.
+	funcs func(g *GoWSDL) template.FuncMap
+}
+
+var (
+	typesTemplates        = templateSet{"types", typesTmpl, (*GoWSDL).typesFuncs}
+	operationsTemplates   = templateSet{"operations", opsTmpl, (*GoWSDL).operationsFuncs}
+	serverTemplates       = templateSet{"server", serverTmpl, (*GoWSDL).serverFuncs}
+	headerTemplates       = templateSet{"header", headerTmpl, (*GoWSDL).headerFuncs}
+	serverHeaderTemplates = templateSet{"server_header", serverHeaderTmpl, (*GoWSDL).serverHeaderFuncs}
+)
+
+var templateSets = []templateSet{
+	typesTemplates,
+	operationsTemplates,
+	serverTemplates,
+	headerTemplates,
+	serverHeaderTemplates,
+}
+
+// templateOverride is a template supplied by the user in place of one of
+// the templates of a set.
+type templateOverride struct {
+	name string
+	// file is the file of fsys the template is read from.
+	file string
+	text string
+	set  string
+}
+
+// WithTemplates overrides the templates code is generated with by the
+// templates of fsys. Each file of the root directory of fsys named after a
+// template with the .tmpl extension, such as Elements.tmpl, replaces that
+// template. The top level templates are types, operations, server, header
+// and server_header; the others are defined by them.
+//
+// Overrides are parsed with the same functions as the templates they
+// replace, plus model, which returns the Model of the WSDL. They are
+// checked by New.
+func WithTemplates(fsys fs.FS) Option {
+	return func(g *GoWSDL) {
+		g.templateFS = fsys
+	}
+}
+
+// parse parses the templates of the set, with the overrides of g.
+func (set templateSet) parse(g *GoWSDL) (*template.Template, error) {
+	funcs := set.funcs(g)
+	funcs["model"] = func() *Model { return g.model }
+
+	tmpl := template.Must(template.New(set.name).Funcs(funcs).Parse(set.text))
+	for _, o := range g.templates {
+		if o.set != set.name {
+			continue
+		}
+		if _, err := tmpl.New(o.name).Parse(o.text); err != nil {
+			return nil, fmt.Errorf("template override %s: %v", o.file, err)
+		}
+	}
+	return tmpl, nil
+}
+
+// render executes the top level template of the set with data.
+func (g *GoWSDL) render(set templateSet, data interface{}) ([]byte, error) {
+	tmpl, err := set.parse(g)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// loadTemplates reads the overrides of g.templateFS, and checks that they
+// override existing templates and that they parse.
+func (g *GoWSDL) loadTemplates() error {
+	if g.templateFS == nil {
+		return nil
+	}
+	files, err := fs.Glob(g.templateFS, "*.tmpl")
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no template overrides, that is *.tmpl files, found")
+	}
+
+	sets := make(map[string]string)
+	var names []string
+	for _, set := range templateSets {
+		tmpl, err := set.parse(g)
+		if err != nil {
+			return err
+		}
+		for _, t := range tmpl.Templates() {
+			sets[t.Name()] = set.name
+			names = append(names, t.Name())
+		}
+	}
+	sort.Strings(names)
+
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), ".tmpl")
+		set, ok := sets[name]
+		if !ok {
+			return fmt.Errorf("template override %s: no template is named %s, templates are %s",
+				file, name, strings.Join(names, ", "))
+		}
+		text, err := fs.ReadFile(g.templateFS, file)
+		if err != nil {
+			return err
+		}
+		g.templates = append(g.templates, templateOverride{name: name, file: file, text: string(text), set: set})
+	}
+
+	// Parse the overrides of each set together, as they may refer to each
+	// other.
+	for _, set := range templateSets {
+		if _, err := set.parse(g); err != nil {
+			return err
+		}
+	}
+	return nil
+}
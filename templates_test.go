@@ -0,0 +1,50 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestTemplateOverrides(t *testing.T) {
+	wsdl := WithLoader("", MapLoader{"orders.wsdl": []byte(modelWSDL)})
+	attributes := "{{range .}}\n// Attribute of {{model.Name}}\n{{fieldName .}} {{attributeType .}} `xml:\"{{.Name}},attr\"`\n{{end}}"
+
+	g, err := New("orders.wsdl", wsdl, WithTemplates(fstest.MapFS{
+		"Attributes.tmpl": {Data: []byte(attributes)},
+		"README":          {Data: []byte("not a template")},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := g.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := result.Files[0].Content
+	for _, want := range []string{"// Attribute of Orders", "Id int32 `xml:\"id,attr\"`"} {
+		if !bytes.Contains(code, []byte(want)) {
+			t.Errorf("generated code does not contain %q:\n%s", want, code)
+		}
+	}
+
+	for name, test := range map[string]struct {
+		file, text string
+		err        string
+	}{
+		"unknown template":  {"Nope.tmpl", "", "template override Nope.tmpl: no template is named Nope, templates are"},
+		"syntax error":      {"Elements.tmpl", "{{range}}", "template override Elements.tmpl: template: Elements:1: missing value for range"},
+		"unknown function":  {"header.tmpl", "{{frob .}}", `template override header.tmpl: template: header:1: function "frob" not defined`},
+		"no template found": {"Elements.txt", "", "no template overrides"},
+	} {
+		_, err := New("orders.wsdl", wsdl, WithTemplates(fstest.MapFS{test.file: {Data: []byte(test.text)}}))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got error %v, want %q", name, err, test.err)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package gowsdl
 
 import (
 	"encoding/xml"
-	"strings"
 )
 
 type traverseMode int32
@@ -13,20 +12,29 @@ const (
 )
 
 type traverser struct {
-	c   *XSDSchema
-	all []*XSDSchema
-	tm  traverseMode
-	// fields used by findNameByType mode
-	typeName             string
-	foundElmName         string
-	conflictingTypeUsage bool
+	c     *XSDSchema
+	index *modelIndex
+	tm    traverseMode
+	// warnf reports unsupported constructs, the first element of the
+	// schema with the given local name and attribute value.
+	warnf func(element, attr, value, format string, args ...interface{})
+	// elementNames records, in findNameByType mode, the name of the
+	// elements of each type, or "" for types of elements with different
+	// names.
+	elementNames map[string]string
 }
 
-func newTraverser(c *XSDSchema, all []*XSDSchema) *traverser {
+func newTraverser(c *XSDSchema, index *modelIndex) *traverser {
 	return &traverser{
-		c:   c,
-		all: all,
-		tm:  refResolution, // default traverse mode is refResolution
+		c:     c,
+		index: index,
+		tm:    refResolution, // default traverse mode is refResolution
+	}
+}
+
+func (t *traverser) warn(element, attr, value, format string, args ...interface{}) {
+	if t.warnf != nil {
+		t.warnf(element, attr, value, format, args...)
 	}
 }
 
@@ -44,46 +52,6 @@ func (t *traverser) traverse() {
 	}
 }
 
-// Given a type, check if there is an Element with that type, and return its name.
-// If multiple elements with identical names of the given type are found,
-// the name is returned.
-// If multiple elements with different names of the given type are found,
-// the original type name is returned instead.
-// If no elements are found, the original type name is returned instead.
-func (t *traverser) findNameByType(name string) string {
-	t.initFindNameByType(name)
-
-	// Search for elements of given type
-	for _, schema := range t.all {
-		for _, elm := range schema.Elements {
-			t.traverseElement(elm)
-		}
-		for _, ct := range schema.ComplexTypes {
-			t.traverseComplexType(ct)
-		}
-		for _, st := range schema.SimpleType {
-			t.traverseSimpleType(st)
-		}
-	}
-
-	// Return found element name if given type is used only once
-	if len(t.foundElmName) > 0 && !t.conflictingTypeUsage {
-		return t.foundElmName
-	}
-
-	// Return original type name
-	// No element found or conflicting element names found
-	return t.typeName
-}
-
-func (t *traverser) initFindNameByType(name string) {
-	// Initialize fields for processing
-	t.tm = findNameByType
-	t.typeName = stripns(name)
-	t.foundElmName = ""
-	t.conflictingTypeUsage = false
-}
-
 func (t *traverser) traverseElements(ct []*XSDElement) {
 	for _, elm := range ct {
 		t.traverseElement(elm)
@@ -107,19 +75,13 @@ func (t *traverser) findElmName(elm *XSDElement) {
 		return
 	}
 
-	// Conflicting type usage already detected -> no need to search any further
-	if t.conflictingTypeUsage {
-		return
-	}
-
-	if stripns(elm.Type) == t.typeName {
-		if len(t.foundElmName) == 0 {
-			// First time usage t.typeName
-			t.foundElmName = elm.Name
-		} else if t.foundElmName != elm.Name {
-			// Duplicate use of t.typeName with different element names
-			t.conflictingTypeUsage = true
-		}
+	typeName := stripns(elm.Type)
+	if name, ok := t.elementNames[typeName]; !ok {
+		// First time usage of typeName
+		t.elementNames[typeName] = elm.Name
+	} else if name != elm.Name {
+		// Duplicate use of typeName with different element names
+		t.elementNames[typeName] = ""
 	}
 }
 
@@ -127,6 +89,26 @@ func (t *traverser) traverseSimpleType(st *XSDSimpleType) {
 }
 
 func (t *traverser) traverseComplexType(ct *XSDComplexType) {
+	if t.tm == refResolution {
+		applyOccurs(ct.Sequence, ct.SequenceOccurs, false)
+		applyOccurs(ct.Choice, ct.ChoiceOccurs, false)
+		applyOccurs(ct.SequenceChoice, ct.SequenceChoiceOccurs, isRepeated(ct.SequenceOccurs.MaxOccurs))
+		applyOccurs(ct.All, ct.AllOccurs, false)
+		ct.Sequence = t.expandGroups(ct.Sequence, ct.Groups, ct.GroupPositions, isOptional(ct.SequenceOccurs.MinOccurs), isRepeated(ct.SequenceOccurs.MaxOccurs), nil)
+		ct.Choice = t.expandGroups(ct.Choice, ct.ChoiceGroups, ct.ChoiceGroupPositions, true, isRepeated(ct.ChoiceOccurs.MaxOccurs), nil)
+		ct.Groups, ct.ChoiceGroups = nil, nil
+		ct.GroupPositions, ct.ChoiceGroupPositions = nil, nil
+
+		ext := &ct.ComplexContent.Extension
+		applyOccurs(ext.Sequence, ext.SequenceOccurs, false)
+		applyOccurs(ext.Choice, ext.ChoiceOccurs, false)
+		applyOccurs(ext.SequenceChoice, ext.SequenceChoiceOccurs, isRepeated(ext.SequenceOccurs.MaxOccurs))
+		ext.Sequence = t.expandGroups(ext.Sequence, ext.Groups, ext.GroupPositions, isOptional(ext.SequenceOccurs.MinOccurs), isRepeated(ext.SequenceOccurs.MaxOccurs), nil)
+		ext.Choice = t.expandGroups(ext.Choice, ext.ChoiceGroups, ext.ChoiceGroupPositions, true, isRepeated(ext.ChoiceOccurs.MaxOccurs), nil)
+		ext.Groups, ext.ChoiceGroups = nil, nil
+		ext.GroupPositions, ext.ChoiceGroupPositions = nil, nil
+	}
+
 	t.traverseElements(ct.Sequence)
 	t.traverseElements(ct.Choice)
 	t.traverseElements(ct.SequenceChoice)
@@ -169,34 +151,109 @@ func (t *traverser) traverseAttribute(attr *XSDAttribute) {
 	}
 }
 
-func (t *traverser) getGlobalAttribute(name string) *XSDAttribute {
-	ref := t.qname(name)
-
-	for _, schema := range t.all {
-		if schema.TargetNamespace == ref.Space {
-			for _, attr := range schema.Attributes {
-				if attr.Name == ref.Local {
-					return attr
-				}
-			}
+// applyOccurs applies the occurrence constraints of a model group to its
+// elements: the elements of an optional group become optional, and those of
+// a group that repeats, or is within one when repeated is set, repeat.
+func applyOccurs(elements []*XSDElement, occurs XSDOccurs, repeated bool) {
+	optional := isOptional(occurs.MinOccurs)
+	repeated = repeated || isRepeated(occurs.MaxOccurs)
+	for _, el := range elements {
+		if optional {
+			el.MinOccurs = "0"
+		}
+		if repeated && !isRepeated(el.MaxOccurs) {
+			el.MaxOccurs = "unbounded"
 		}
 	}
+}
 
-	return nil
+// expandGroups returns the elements of a sequence or a choice with copies of
+// the elements of its model groups in place of the references to them,
+// positions holding the number of elements preceding each reference. The
+// occurrence constraints of each reference, and those given for the
+// sequence or choice, optional for a choice, are applied to the copies.
+// seen holds the groups being expanded, to stop at recursive references.
+func (t *traverser) expandGroups(elements []*XSDElement, groups []*XSDGroup, positions []int, optional, repeated bool, seen map[string]bool) []*XSDElement {
+	if len(groups) == 0 {
+		return elements
+	}
+	if seen == nil {
+		seen = make(map[string]bool)
+	}
+	position := func(i int) int {
+		if i < len(positions) {
+			return positions[i]
+		}
+		return len(elements)
+	}
+
+	var expanded []*XSDElement
+	next := 0
+	for i := 0; i <= len(elements); i++ {
+		for ; next < len(groups) && position(next) <= i; next++ {
+			expanded = append(expanded, t.expandGroup(groups[next], optional, repeated, seen)...)
+		}
+		if i < len(elements) {
+			expanded = append(expanded, elements[i])
+		}
+	}
+	return expanded
 }
 
-// qname resolves QName into xml.Name.
-func (t *traverser) qname(name string) (qname xml.Name) {
-	x := strings.SplitN(name, ":", 2)
-	if len(x) == 1 {
-		qname.Local = x[0]
-	} else {
-		qname.Local = x[1]
-		qname.Space = x[0]
-		if ns, ok := t.c.Xmlns[qname.Space]; ok {
-			qname.Space = ns
+func (t *traverser) expandGroup(group *XSDGroup, optional, repeated bool, seen map[string]bool) []*XSDElement {
+	optional = optional || isOptional(group.MinOccurs)
+	repeated = repeated || isRepeated(group.MaxOccurs)
+
+	if group.Ref != "" {
+		ref := t.qname(group.Ref)
+		if seen[ref.Space+" "+ref.Local] {
+			t.warn("group", "ref", group.Ref, "Group %q references itself, ignoring it", group.Ref)
+			return nil
 		}
+
+		def := t.getGlobalGroup(ref)
+		if def == nil {
+			t.warn("group", "ref", group.Ref, "Group %q not found", group.Ref)
+			return nil
+		}
+
+		seen[ref.Space+" "+ref.Local] = true
+		defer delete(seen, ref.Space+" "+ref.Local)
+
+		// The reference carries the occurrence constraints, the
+		// definition carries the content.
+		group = def
+	}
+
+	copies := func(content []XSDElement, optional bool) []*XSDElement {
+		var elements []*XSDElement
+		for _, el := range content {
+			el := el
+			if optional {
+				el.MinOccurs = "0"
+			}
+			if repeated && !isRepeated(el.MaxOccurs) {
+				el.MaxOccurs = "unbounded"
+			}
+			elements = append(elements, &el)
+		}
+		return elements
 	}
+	elements := t.expandGroups(copies(group.Sequence, optional), group.Groups, group.GroupPositions, optional, repeated, seen)
+	elements = append(elements, copies(group.All, optional)...)
+	return append(elements, t.expandGroups(copies(group.Choice, true), group.ChoiceGroups, group.ChoiceGroupPositions, true, repeated, seen)...)
+}
+
+func (t *traverser) getGlobalGroup(ref xml.Name) *XSDGroup {
+	return t.index.groups[ref]
+}
+
+func (t *traverser) getGlobalAttribute(name string) *XSDAttribute {
+	return t.index.attributes[t.qname(name)]
+}
 
-	return qname
+// qname resolves QName into xml.Name. Unprefixed names are in the default
+// namespace, which chameleon schemas get from the schema including them.
+func (t *traverser) qname(name string) xml.Name {
+	return xmlName(t.c.Xmlns, name)
 }
@@ -0,0 +1,348 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"encoding/xml"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"sort"
+	"strings"
+)
+
+// GoType is a Go type XSD types are mapped to in place of the generated
+// ones. Values of the type are marshaled by encoding/xml, so it must
+// implement xml.Marshaler and xml.Unmarshaler, or encoding.TextMarshaler and
+// encoding.TextUnmarshaler, as time.Time does. Types used by attributes must
+// implement xml.MarshalerAttr and xml.UnmarshalerAttr, or the text ones.
+type GoType struct {
+	// Type is the type as written in the generated code, such as
+	// "time.Time" or "decimal.Decimal".
+	Type string
+	// Import is the path of the package of Type, such as
+	// "github.com/shopspring/decimal". It is empty for predeclared types.
+	Import string
+}
+
+// WithTypes maps XSD types to Go types. Keys are qualified XSD type names,
+// either prefix:name, with a prefix declared by the WSDL or its schemas, or
+// {namespace}name; the prefixes xs and xsd stand for the XML Schema
+// namespace unless declared otherwise. Unqualified keys name built-in XSD
+// types, such as dateTime or decimal, or else the declared type of that
+// name, such as Money, when a single namespace declares one. Built-in types
+// are matched regardless of case. Declared types that are mapped are not
+// generated.
+func WithTypes(types map[string]GoType) Option {
+	return func(g *GoWSDL) {
+		if g.types == nil {
+			g.types = make(map[string]GoType)
+		}
+		for xsdType, goType := range types {
+			g.types[strings.TrimSpace(xsdType)] = goType
+		}
+	}
+}
+
+// WithNamespaces takes the types and elements declared in the given target
+// namespaces from the Go packages with the given import paths, instead of
+// generating them, as when the types of a namespace shared by several
+// services are generated once in a package of their own. The last element
+// of an import path must be the name of its package, and the names of the
+// types are those they are generated with in it, with the same naming
+// settings.
+func WithNamespaces(packages map[string]string) Option {
+	return func(g *GoWSDL) {
+		if g.namespaces == nil {
+			g.namespaces = make(map[string]string)
+		}
+		for ns, importPath := range packages {
+			g.namespaces[ns] = importPath
+		}
+	}
+}
+
+// checkTypes checks that the types XSD types are mapped to, and the
+// packages namespaces are mapped to, are valid.
+func (g *GoWSDL) checkTypes() error {
+	for ns, importPath := range g.namespaces {
+		if !token.IsIdentifier(path.Base(importPath)) || strings.ContainsAny(importPath, " \t\"`\\") {
+			return fmt.Errorf("package of namespace %s: %q is not an import path ending with a package name", ns, importPath)
+		}
+	}
+	for xsdType, goType := range g.types {
+		if xsdType == "" {
+			return fmt.Errorf("type mapping to %s: the XSD type is empty", goType.Type)
+		}
+		if strings.HasPrefix(xsdType, "{") && !strings.Contains(xsdType, "}") {
+			return fmt.Errorf("type mapping of %s: the namespace is not closed by }", xsdType)
+		}
+		if _, err := parser.ParseExpr(goType.Type); err != nil {
+			return fmt.Errorf("type mapping of %s: %q is not a Go type: %v", xsdType, goType.Type, err)
+		}
+		if goType.Import != "" && strings.ContainsAny(goType.Import, " \t\"`\\") {
+			return fmt.Errorf("type mapping of %s: %q is not an import path", xsdType, goType.Import)
+		}
+	}
+	return nil
+}
+
+// resolveTypes resolves the XSD type names the type mappings are given for,
+// once the schemas are loaded. Mappings that cannot be resolved are
+// reported and ignored.
+func (g *GoWSDL) resolveTypes() {
+	if len(g.types) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(g.types))
+	for key := range g.types {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	g.typeNames = make(map[xml.Name]GoType)
+	for _, key := range keys {
+		name, ok, err := g.typeName(key)
+		if err != nil {
+			g.warnf(construct{}, "Type mapping of %s is ignored: %v", key, err)
+			continue
+		}
+		if ok {
+			g.typeNames[name] = g.types[key]
+		}
+	}
+}
+
+// typeName returns the qualified name of the XSD type a mapping is given
+// for, and false for an unqualified name no schema declares.
+func (g *GoWSDL) typeName(key string) (xml.Name, bool, error) {
+	if strings.HasPrefix(key, "{") {
+		i := strings.Index(key, "}")
+		return builtinName(xml.Name{Space: key[1:i], Local: key[i+1:]}), true, nil
+	}
+	if i := strings.Index(key, ":"); i >= 0 {
+		ns, ok := g.prefixNamespace(key[:i])
+		if !ok {
+			return xml.Name{}, false, fmt.Errorf("prefix %s is not declared", key[:i])
+		}
+		return builtinName(xml.Name{Space: ns, Local: key[i+1:]}), true, nil
+	}
+	if _, builtin := xsd2GoTypes[strings.ToLower(key)]; builtin {
+		return builtinName(xml.Name{Space: xmlschema11, Local: key}), true, nil
+	}
+
+	var names []xml.Name
+	declare := func(schema *XSDSchema) {
+		name := xml.Name{Space: schema.TargetNamespace, Local: key}
+		for _, n := range names {
+			if n == name {
+				return
+			}
+		}
+		names = append(names, name)
+	}
+	for _, schema := range g.wsdl.Types.Schemas {
+		for _, st := range schema.SimpleType {
+			if st.Name == key {
+				declare(schema)
+			}
+		}
+		for _, ct := range schema.ComplexTypes {
+			if ct.Name == key {
+				declare(schema)
+			}
+		}
+	}
+	switch len(names) {
+	case 0:
+		return xml.Name{}, false, nil
+	case 1:
+		return names[0], true, nil
+	}
+	return xml.Name{}, false, fmt.Errorf("types named %s are declared in namespaces %s and %s, qualify it", key, names[0].Space, names[1].Space)
+}
+
+// prefixNamespace returns the namespace prefix is declared for by the WSDL,
+// or else by the first schema declaring it.
+func (g *GoWSDL) prefixNamespace(prefix string) (string, bool) {
+	if ns, ok := g.wsdl.Xmlns[prefix]; ok {
+		return ns, true
+	}
+	for _, schema := range g.wsdl.Types.Schemas {
+		if ns, ok := schema.Xmlns[prefix]; ok {
+			return ns, true
+		}
+	}
+	if prefix == "xs" || prefix == "xsd" {
+		return xmlschema11, true
+	}
+	return "", false
+}
+
+// builtinName returns name, in lower case when it is a built-in XSD type,
+// which are matched regardless of case.
+func builtinName(name xml.Name) xml.Name {
+	if name.Space == xmlschema11 {
+		name.Local = strings.ToLower(name.Local)
+	}
+	return name
+}
+
+// mappedType returns the Go type an XSD type reference, made where the
+// namespace declarations xmlns are in scope, is mapped to. References that
+// cannot be resolved, made without xmlns or with an undeclared prefix, are
+// matched by local name when a single mapping has it.
+func (g *GoWSDL) mappedType(xmlns map[string]string, xsdType string) (string, bool) {
+	if len(g.typeNames) == 0 {
+		return "", false
+	}
+	if name, ok := typeRef(xmlns, xsdType); ok {
+		goType, ok := g.typeNames[name]
+		return goType.Type, ok
+	}
+
+	local := stripns(xsdType)
+	var found []GoType
+	for name, goType := range g.typeNames {
+		if name.Local == local || (name.Space == xmlschema11 && name.Local == strings.ToLower(local)) {
+			found = append(found, goType)
+		}
+	}
+	if len(found) != 1 {
+		return "", false
+	}
+	return found[0].Type, true
+}
+
+// typeRef resolves an XSD type reference with the namespace declarations
+// xmlns, and returns false when its prefix, or the default namespace for
+// unprefixed ones, is not declared.
+func typeRef(xmlns map[string]string, xsdType string) (xml.Name, bool) {
+	prefix := ""
+	if i := strings.Index(xsdType, ":"); i >= 0 {
+		prefix = xsdType[:i]
+	}
+	if _, ok := xmlns[prefix]; !ok {
+		return xml.Name{}, false
+	}
+	return builtinName(xmlName(xmlns, xsdType)), true
+}
+
+// dropMappedTypes removes the declared types that are mapped to Go types
+// from the schemas, so that they are not generated.
+func (g *GoWSDL) dropMappedTypes() {
+	if len(g.typeNames) == 0 {
+		return
+	}
+	mapped := func(schema *XSDSchema, name string) bool {
+		_, ok := g.typeNames[xml.Name{Space: schema.TargetNamespace, Local: name}]
+		return ok
+	}
+	for _, schema := range g.wsdl.Types.Schemas {
+		simpleTypes := schema.SimpleType[:0]
+		for _, st := range schema.SimpleType {
+			if !mapped(schema, st.Name) {
+				simpleTypes = append(simpleTypes, st)
+			}
+		}
+		schema.SimpleType = simpleTypes
+
+		complexTypes := schema.ComplexTypes[:0]
+		for _, ct := range schema.ComplexTypes {
+			if !mapped(schema, ct.Name) {
+				complexTypes = append(complexTypes, ct)
+			}
+		}
+		schema.ComplexTypes = complexTypes
+	}
+}
+
+// namespacePackage returns the import path of the package the types of
+// namespace are taken from, if any.
+func (g *GoWSDL) namespacePackage(namespace string) (string, bool) {
+	importPath, ok := g.namespaces[namespace]
+	return importPath, ok && namespace != ""
+}
+
+// importedGoTypes returns the types of imported packages the generated code
+// may use: those XSD types are mapped to, and a type of each package the
+// types of a namespace are taken from.
+func (g *GoWSDL) importedGoTypes() []GoType {
+	var types []GoType
+	for _, goType := range g.types {
+		types = append(types, goType)
+	}
+	if g.symbols != nil {
+		for importPath, name := range g.symbols.imported {
+			types = append(types, GoType{Type: name, Import: importPath})
+		}
+	}
+	return types
+}
+
+// typeImports returns a function returning the sorted packages the types
+// XSD types are mapped to belong to, but for the packages a template imports
+// anyway.
+func (g *GoWSDL) typeImports(imported ...string) func() []string {
+	return func() []string {
+		seen := make(map[string]bool)
+		for _, path := range imported {
+			seen[path] = true
+		}
+		var imports []string
+		for _, goType := range g.importedGoTypes() {
+			if path := goType.Import; path != "" && !seen[path] {
+				seen[path] = true
+				imports = append(imports, path)
+			}
+		}
+		sort.Strings(imports)
+		return imports
+	}
+}
+
+// importedTypes returns the sorted mapped types of imported packages, which
+// the generated code refers to so that their imports are used.
+func (g *GoWSDL) importedTypes() []string {
+	seen := make(map[string]bool)
+	var types []string
+	for _, goType := range g.importedGoTypes() {
+		if goType.Import != "" && !seen[goType.Type] {
+			seen[goType.Type] = true
+			types = append(types, goType.Type)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+// importName returns the name of the package imported from path: the one
+// the types mapped to it are qualified with, or the last element of path.
+func (g *GoWSDL) importName(importPath string) string {
+	for _, goType := range g.importedGoTypes() {
+		if goType.Import != importPath {
+			continue
+		}
+		expr, err := parser.ParseExpr(goType.Type)
+		if err != nil {
+			continue
+		}
+		var name string
+		ast.Inspect(expr, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok && name == "" {
+				if id, ok := sel.X.(*ast.Ident); ok {
+					name = id.Name
+				}
+			}
+			return name == ""
+		})
+		if name != "" {
+			return name
+		}
+	}
+	return path.Base(importPath)
+}
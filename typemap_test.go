@@ -0,0 +1,171 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package gowsdl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTypeMappings(t *testing.T) {
+	g, err := New("orders.wsdl", WithLoader("", MapLoader{"orders.wsdl": []byte(modelWSDL)}), WithTypes(map[string]GoType{
+		"xs:int":   {Type: "decimal.Decimal", Import: "github.com/shopspring/decimal"},
+		"o:Status": {Type: "orders.Status", Import: "example.com/orders"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := g.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, server := result.Files[0].Content, result.Files[1].Content
+	for _, want := range []string{
+		"\t\"example.com/orders\"\n",
+		"\t\"github.com/shopspring/decimal\"\n",
+		"var _ decimal.Decimal\n",
+		"Status orders.Status `xml:\"status,omitempty\" json:\"status,omitempty\"`",
+		"Id decimal.Decimal `xml:\"urn:orders id,attr,omitempty\" json:\"id,omitempty\"`",
+		"GetOrder(request *decimal.Decimal) (*Order, error)",
+	} {
+		if !bytes.Contains(client, []byte(want)) {
+			t.Errorf("generated client does not contain %q:\n%s", want, client)
+		}
+	}
+	if bytes.Contains(client, []byte("type Status ")) {
+		t.Error("mapped type Status is generated")
+	}
+	for _, want := range []string{
+		"\t\"github.com/shopspring/decimal\"\n",
+		"Decimal *decimal.Decimal",
+	} {
+		if !bytes.Contains(server, []byte(want)) {
+			t.Errorf("generated server does not contain %q:\n%s", want, server)
+		}
+	}
+
+	_, err = New("orders.wsdl", WithTypes(map[string]GoType{"Status": {Type: "not a type"}}))
+	if err == nil || !strings.Contains(err.Error(), "is not a Go type") {
+		t.Errorf("got error %v, want one about the Go type", err)
+	}
+}
+
+const moneyWSDL = `<?xml version="1.0" encoding="UTF-8"?>
+<definitions name="Bank" targetNamespace="urn:bank:wsdl"
+  xmlns="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:tns="urn:bank:wsdl"
+  xmlns:a="urn:accounts"
+  xmlns:l="urn:ledger">
+  <types>
+    <xs:schema targetNamespace="urn:ledger" elementFormDefault="qualified"
+      xmlns:xs="http://www.w3.org/2001/XMLSchema">
+      <xs:complexType name="Money">
+        <xs:sequence><xs:element name="cents" type="xs:long"/></xs:sequence>
+      </xs:complexType>
+      <xs:simpleType name="dateTime">
+        <xs:restriction base="xs:string"/>
+      </xs:simpleType>
+    </xs:schema>
+    <xs:schema targetNamespace="urn:accounts" elementFormDefault="qualified"
+      xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:a="urn:accounts" xmlns:l="urn:ledger">
+      <xs:simpleType name="Money">
+        <xs:restriction base="xs:decimal"/>
+      </xs:simpleType>
+      <xs:complexType name="Account">
+        <xs:sequence>
+          <xs:element name="balance" type="a:Money"/>
+          <xs:element name="entry" type="l:Money"/>
+          <xs:element name="opened" type="xs:dateTime"/>
+          <xs:element name="booked" type="l:dateTime"/>
+        </xs:sequence>
+      </xs:complexType>
+    </xs:schema>
+  </types>
+</definitions>`
+
+func TestQualifiedTypeMappings(t *testing.T) {
+	g, err := New("bank.wsdl", WithLoader("", MapLoader{"bank.wsdl": []byte(moneyWSDL)}), WithTypes(map[string]GoType{
+		"a:Money":     {Type: "money.Amount", Import: "example.com/money"},
+		"xs:dateTime": {Type: "time.Time", Import: "time"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := g.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Types of the same name in other namespaces are neither replaced nor
+	// dropped.
+	code := result.Files[0].Content
+	for _, want := range []string{
+		"Balance money.Amount `",
+		"Entry *Money `",
+		"type Money struct",
+		"Opened time.Time `",
+		"type DateTime string",
+	} {
+		if !bytes.Contains(code, []byte(want)) {
+			t.Errorf("generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if bytes.Contains(code, []byte("Booked time.Time")) {
+		t.Errorf("dateTime of urn:ledger is mapped like xs:dateTime:\n%s", code)
+	}
+
+	// An unqualified name declared in several namespaces is ambiguous.
+	g, err = New("bank.wsdl", WithLoader("", MapLoader{"bank.wsdl": []byte(moneyWSDL)}), WithTypes(map[string]GoType{
+		"Money": {Type: "money.Amount", Import: "example.com/money"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	// The Money types of both namespaces are generated, one of them renamed.
+	diagnostics := g.Diagnostics()
+	if len(diagnostics) != 2 || !strings.Contains(diagnostics[0].Message, "urn:ledger and urn:accounts") ||
+		!strings.HasPrefix(diagnostics[1].Message, "Renamed ") {
+		t.Errorf("got diagnostics %v, want one about the ambiguous Money and its rename", diagnostics)
+	}
+}
+
+func TestNamespacePackages(t *testing.T) {
+	g, err := New("bank.wsdl", WithLoader("", MapLoader{"bank.wsdl": []byte(moneyWSDL)}),
+		WithNamespaces(map[string]string{"urn:ledger": "example.com/bank/ledger"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := g.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code := result.Files[0].Content
+	for _, want := range []string{
+		"\t\"example.com/bank/ledger\"\n",
+		"Entry *ledger.Money `",
+		"Balance *Money `",
+		"type Money float64",
+	} {
+		if !bytes.Contains(code, []byte(want)) {
+			t.Errorf("generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	for _, unwanted := range []string{"type Money struct", "Cents"} {
+		if bytes.Contains(code, []byte(unwanted)) {
+			t.Errorf("generated code contains %q of the ledger namespace:\n%s", unwanted, code)
+		}
+	}
+
+	_, err = New("bank.wsdl", WithNamespaces(map[string]string{"urn:ledger": "example.com/bank-ledger"}))
+	if err == nil || !strings.Contains(err.Error(), "package name") {
+		t.Errorf("got error %v, want one about the package name", err)
+	}
+}
@@ -6,7 +6,7 @@ package gowsdl
 
 var typesTmpl = `
 {{define "SimpleType"}}
-	{{$typeName := replaceReservedWords .Name | makePublic}}
+	{{$typeName := typeName .}}
 	{{if .Doc}} {{.Doc | comment}} {{end}}
 	{{if ne .List.ItemType ""}}
 		type {{$typeName}} []{{toGoType .List.ItemType false | removePointerFromType}}
@@ -23,9 +23,9 @@ var typesTmpl = `
 	{{if .Restriction.Enumeration}}
 	const (
 		{{with .Restriction}}
-			{{range .Enumeration}}
+			{{range $i, $v := .Enumeration}}
 				{{if .Doc}} {{.Doc | comment}} {{end}}
-				{{$typeName}}{{$value := replaceReservedWords .Value}}{{$value | makePublic}} {{$typeName}} = "{{goString .Value}}" {{end}}
+				{{enumName $typeName $i}} {{$typeName}} = "{{goString .Value}}" {{end}}
 		{{end}}
 	)
 	{{end}}
@@ -47,11 +47,7 @@ var typesTmpl = `
     {{ $targetNamespace := getNS }}
 	{{range .}}
 		{{if .Doc}} {{.Doc | comment}} {{end}}
-		{{ if ne .Type "" }}
-			{{ normalize .Name | makeFieldPublic}} {{toGoType .Type false}} ` + "`" + `xml:"{{with $targetNamespace}}{{.}} {{end}}{{.Name}},attr,omitempty" json:"{{.Name}},omitempty"` + "`" + `
-		{{ else }}
-			{{ normalize .Name | makeFieldPublic}} string ` + "`" + `xml:"{{with $targetNamespace}}{{.}} {{end}}{{.Name}},attr,omitempty" json:"{{.Name}},omitempty"` + "`" + `
-		{{ end }}
+		{{fieldName .}} {{attributeType .}} ` + "`" + `xml:"{{with $targetNamespace}}{{.}} {{end}}{{.Name}},attr,omitempty" json:"{{jsonKey .}},omitempty"{{fieldTags .}}` + "`" + `
 	{{end}}
 {{end}}
 
@@ -61,49 +57,70 @@ var typesTmpl = `
 {{end}}
 
 {{define "ComplexTypeInline"}}
-	{{replaceReservedWords .Name | makePublic}} {{if eq .MaxOccurs "unbounded"}}[]{{end}}struct {
-	{{with .ComplexType}}
-		{{if ne .ComplexContent.Extension.Base ""}}
-			{{template "ComplexContent" .ComplexContent}}
-		{{else if ne .SimpleContent.Extension.Base ""}}
-			{{template "SimpleContent" .SimpleContent}}
-		{{else}}
-			{{template "Elements" .Sequence}}
-			{{template "Elements" .Choice}}
-			{{template "Elements" .SequenceChoice}}
-			{{template "Elements" .All}}
-			{{template "Attributes" .Attributes}}
+	{{with inlineTypeName .}}
+		{{fieldName $}} {{inlineShape $}}{{.}} ` + "`" + `xml:"{{$.Name}},omitempty" json:"{{jsonKey $}},omitempty"` + "`" + `
+	{{else}}
+		{{fieldName .}} {{inlineShape .}}struct {
+		{{with .ComplexType}}
+			{{template "ComplexTypeInlineBody" .}}
+		{{end}}
+		} ` + "`" + `xml:"{{.Name}},omitempty" json:"{{jsonKey .}},omitempty"{{fieldTags .}}` + "`" + `
+	{{end}}
+{{end}}
+
+{{define "ComplexTypeInlineBody"}}
+	{{if ne .ComplexContent.Extension.Base ""}}
+		{{template "ComplexContent" .ComplexContent}}
+	{{else if ne .SimpleContent.Extension.Base ""}}
+		{{template "SimpleContent" .SimpleContent}}
+	{{else}}
+		{{template "Elements" .Sequence}}
+		{{template "Elements" .Choice}}
+		{{template "Elements" .SequenceChoice}}
+		{{template "Elements" .All}}
+		{{template "Attributes" .Attributes}}
+		{{if unknownFields}}
+			{{template "Any" .Any}}
 		{{end}}
 	{{end}}
-	} ` + "`" + `xml:"{{.Name}},omitempty" json:"{{.Name}},omitempty"` + "`" + `
+	{{template "UnknownFields" .}}
 {{end}}
 
 {{define "Elements"}}
 	{{range .}}
 		{{if ne .Ref ""}}
-			{{removeNS .Ref | replaceReservedWords  | makePublic}} {{if eq .MaxOccurs "unbounded"}}[]{{end}}{{toGoType .Ref .Nillable }} ` + "`" + `xml:"{{.Ref | removeNS}},omitempty" json:"{{.Ref | removeNS}},omitempty"` + "`" + `
+			{{fieldName .}} {{fieldType .}} ` + "`" + `xml:"{{.Ref | removeNS}},omitempty" json:"{{jsonKey .}},omitempty"{{fieldTags .}}` + "`" + `
 		{{else}}
 		{{if not .Type}}
 			{{if .SimpleType}}
 				{{if .Doc}} {{.Doc | comment}} {{end}}
-				{{if ne .SimpleType.List.ItemType ""}}
-					{{ normalize .Name | makeFieldPublic}} []{{toGoType .SimpleType.List.ItemType false}} ` + "`" + `xml:"{{.Name}},omitempty" json:"{{.Name}},omitempty"` + "`" + `
-				{{else}}
-					{{ normalize .Name | makeFieldPublic}} {{toGoType .SimpleType.Restriction.Base false}} ` + "`" + `xml:"{{.Name}},omitempty" json:"{{.Name}},omitempty"` + "`" + `
-				{{end}}
+				{{fieldName .}} {{fieldType .}} ` + "`" + `xml:"{{.Name}},omitempty" json:"{{jsonKey .}},omitempty"{{fieldTags .}}` + "`" + `
 			{{else}}
 				{{template "ComplexTypeInline" .}}
 			{{end}}
 		{{else}}
 			{{if .Doc}}{{.Doc | comment}} {{end}}
-			{{replaceAttrReservedWords .Name | makeFieldPublic}} {{if eq .MaxOccurs "unbounded"}}[]{{end}}{{toGoType .Type .Nillable }} ` + "`" + `xml:"{{.Name}},omitempty" json:"{{.Name}},omitempty"` + "`" + ` {{end}}
+			{{fieldName .}} {{fieldType .}} ` + "`" + `xml:"{{.Name}},omitempty" json:"{{jsonKey .}},omitempty"{{fieldTags .}}` + "`" + ` {{end}}
 		{{end}}
 	{{end}}
 {{end}}
 
 {{define "Any"}}
-	{{range .}}
-		Items     []string ` + "`" + `xml:",any" json:"items,omitempty"` + "`" + `
+	{{with .}}
+		{{if unknownFields}}
+			Items     []soap.AnyElement ` + "`" + `xml:",any" json:"items,omitempty"` + "`" + `
+		{{else}}
+			Items     []string ` + "`" + `xml:",any" json:"items,omitempty"` + "`" + `
+		{{end}}
+	{{end}}
+{{end}}
+
+{{define "UnknownFields"}}
+	{{if unknownFields}}
+		{{if not .Any}}
+			UnknownElements []soap.AnyElement ` + "`" + `xml:",any" json:"-"` + "`" + `
+		{{end}}
+		UnknownAttrs []soap.AnyAttr ` + "`" + `xml:",any,attr" json:"-"` + "`" + `
 	{{end}}
 {{end}}
 
@@ -116,7 +133,7 @@ var typesTmpl = `
 
 	{{range .Elements}}
 		{{$name := .Name}}
-		{{$typeName := replaceReservedWords $name | makePublic}}
+		{{$typeName := typeName .}}
 		{{if not .Type}}
 			{{/* ComplexTypeLocal */}}
 			{{with .ComplexType}}
@@ -134,6 +151,7 @@ var typesTmpl = `
 						{{template "Elements" .All}}
 						{{template "Attributes" .Attributes}}
 					{{end}}
+					{{template "UnknownFields" .}}
 				}
 			{{end}}
 			{{/* SimpleTypeLocal */}}
@@ -154,9 +172,9 @@ var typesTmpl = `
 				{{if .Restriction.Enumeration}}
 				const (
 					{{with .Restriction}}
-						{{range .Enumeration}}
+						{{range $i, $v := .Enumeration}}
 							{{if .Doc}} {{.Doc | comment}} {{end}}
-							{{$typeName}}{{$value := replaceReservedWords .Value}}{{$value | makePublic}} {{$typeName}} = "{{goString .Value}}" {{end}}
+							{{enumName $typeName $i}} {{$typeName}} = "{{goString .Value}}" {{end}}
 					{{end}}
 				)
 				{{end}}
@@ -196,7 +214,7 @@ var typesTmpl = `
 
 	{{range .ComplexTypes}}
 		{{/* ComplexTypeGlobal */}}
-		{{$typeName := replaceReservedWords .Name | makePublic}}
+		{{$typeName := typeName .}}
 		{{if and (eq (len .SimpleContent.Extension.Attributes) 0) (eq (toGoType .SimpleContent.Extension.Base false) "string") }}
 			type {{$typeName}} string
 		{{else}}
@@ -218,8 +236,17 @@ var typesTmpl = `
 					{{template "Elements" .All}}
 					{{template "Attributes" .Attributes}}
 				{{end}}
+				{{template "UnknownFields" .}}
 			}
 		{{end}}
 	{{end}}
+
+	{{range inlineTypes .}}
+		{{/* ComplexTypeInlineNamed */}}
+		{{if .Doc}} {{.Doc | comment}} {{end}}
+		type {{inlineTypeName .}} struct {
+			{{template "ComplexTypeInlineBody" .ComplexType}}
+		}
+	{{end}}
 {{end}}
 `
@@ -6,6 +6,8 @@ package gowsdl
 
 import (
 	"encoding/xml"
+	"io"
+	"strings"
 )
 
 const xmlschema11 = "http://www.w3.org/2001/XMLSchema"
@@ -20,11 +22,14 @@ type XSDSchema struct {
 	TargetNamespace    string            `xml:"targetNamespace,attr"`
 	ElementFormDefault string            `xml:"elementFormDefault,attr"`
 	Includes           []*XSDInclude     `xml:"include"`
+	Redefines          []*XSDRedefine    `xml:"redefine"`
+	Overrides          []*XSDRedefine    `xml:"override"`
 	Imports            []*XSDImport      `xml:"import"`
 	Elements           []*XSDElement     `xml:"element"`
 	Attributes         []*XSDAttribute   `xml:"attribute"`
 	ComplexTypes       []*XSDComplexType `xml:"complexType"` // global
 	SimpleType         []*XSDSimpleType  `xml:"simpleType"`
+	Groups             []*XSDGroup       `xml:"group"`
 }
 
 // UnmarshalXML implements interface xml.Unmarshaler for XSDSchema.
@@ -36,6 +41,10 @@ func (s *XSDSchema) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 			s.Xmlns[attr.Name.Local] = attr.Value
 			continue
 		}
+		if attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+			s.Xmlns[""] = attr.Value
+			continue
+		}
 
 		switch attr.Name.Local {
 		case "version":
@@ -68,6 +77,16 @@ Loop:
 					return err
 				}
 				s.Includes = append(s.Includes, x)
+			case "redefine", "override":
+				x := new(XSDRedefine)
+				if err := d.DecodeElement(x, &t); err != nil {
+					return err
+				}
+				if t.Name.Local == "redefine" {
+					s.Redefines = append(s.Redefines, x)
+				} else {
+					s.Overrides = append(s.Overrides, x)
+				}
 			case "import":
 				x := new(XSDImport)
 				if err := d.DecodeElement(x, &t); err != nil {
@@ -98,6 +117,12 @@ Loop:
 					return err
 				}
 				s.SimpleType = append(s.SimpleType, x)
+			case "group":
+				x := new(XSDGroup)
+				if err := d.DecodeElement(x, &t); err != nil {
+					return err
+				}
+				s.Groups = append(s.Groups, x)
 			default:
 				d.Skip()
 				continue Loop
@@ -115,6 +140,18 @@ type XSDInclude struct {
 	SchemaLocation string `xml:"schemaLocation,attr"`
 }
 
+// XSDRedefine represents schema redefines and overrides. They include a
+// schema like XSDInclude, replacing the components of the same name with
+// the ones they declare.
+type XSDRedefine struct {
+	SchemaLocation string            `xml:"schemaLocation,attr"`
+	SimpleTypes    []*XSDSimpleType  `xml:"simpleType"`
+	ComplexTypes   []*XSDComplexType `xml:"complexType"`
+	Groups         []*XSDGroup       `xml:"group"`
+	Elements       []*XSDElement     `xml:"element"`
+	Attributes     []*XSDAttribute   `xml:"attribute"`
+}
+
 // XSDImport represents XSD imports within the main schema.
 type XSDImport struct {
 	XMLName        xml.Name `xml:"import"`
@@ -161,22 +198,156 @@ type XSDComplexType struct {
 	SimpleContent  XSDSimpleContent  `xml:"simpleContent"`
 	Attributes     []*XSDAttribute   `xml:"attribute"`
 	Any            []*XSDAny         `xml:"sequence>any"`
+	Groups         []*XSDGroup       `xml:"sequence>group"`
+	ChoiceGroups   []*XSDGroup       `xml:"choice>group"`
+
+	// Occurrence constraints of the model groups the elements belong to.
+	SequenceOccurs       XSDOccurs `xml:"-"`
+	ChoiceOccurs         XSDOccurs `xml:"-"`
+	SequenceChoiceOccurs XSDOccurs `xml:"-"`
+	AllOccurs            XSDOccurs `xml:"-"`
+
+	// Number of elements preceding each group reference in the sequence
+	// or the choice, for groups to be expanded in place.
+	GroupPositions       []int `xml:"-"`
+	ChoiceGroupPositions []int `xml:"-"`
+}
+
+// UnmarshalXML implements interface xml.Unmarshaler for XSDComplexType.
+func (ct *XSDComplexType) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type complexType XSDComplexType
+	groups, err := decodeModelGroups(d, start, (*complexType)(ct))
+	if err != nil {
+		return err
+	}
+	ct.SequenceOccurs = groups.occurs["sequence"]
+	ct.ChoiceOccurs = groups.occurs["choice"]
+	ct.SequenceChoiceOccurs = groups.occurs["sequence>choice"]
+	ct.AllOccurs = groups.occurs["all"]
+	ct.GroupPositions = groups.positions["sequence"]
+	ct.ChoiceGroupPositions = groups.positions["choice"]
+	return nil
+}
+
+// XSDOccurs holds the occurrence constraints of a model group: sequence,
+// choice or all.
+type XSDOccurs struct {
+	MinOccurs string
+	MaxOccurs string
+}
+
+// modelGroups describes the model groups of a complex type, an extension or
+// a group definition, by path: sequence, choice, all and sequence>choice.
+type modelGroups struct {
+	occurs map[string]XSDOccurs
+	// positions holds the number of elements preceding each group
+	// reference of a model group.
+	positions map[string][]int
+}
+
+// decodeModelGroups decodes the element start into v, and returns its model
+// groups. encoding/xml cannot map both the attributes of a model group and
+// the elements within it, nor tell in which order elements and group
+// references come.
+func decodeModelGroups(d *xml.Decoder, start xml.StartElement, v interface{}) (*modelGroups, error) {
+	tokens := tokenReplay{start.Copy()}
+	groups := &modelGroups{
+		occurs:    make(map[string]XSDOccurs),
+		positions: make(map[string][]int),
+	}
+	elements := make(map[string]int)
+	var path []string
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, xml.CopyToken(tok))
+
+		if _, ok := tok.(xml.EndElement); ok {
+			if len(path) == 0 {
+				break
+			}
+			path = path[:len(path)-1]
+			continue
+		}
+		t, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		path = append(path, t.Name.Local)
+		if len(path) == 2 && (path[0] == "sequence" || path[0] == "choice") {
+			switch path[1] {
+			case "element":
+				elements[path[0]]++
+			case "group":
+				groups.positions[path[0]] = append(groups.positions[path[0]], elements[path[0]])
+			}
+		}
+		if key := strings.Join(path, ">"); key == "sequence" || key == "choice" || key == "all" || key == "sequence>choice" {
+			var o XSDOccurs
+			for _, attr := range t.Attr {
+				switch attr.Name.Local {
+				case "minOccurs":
+					o.MinOccurs = attr.Value
+				case "maxOccurs":
+					o.MaxOccurs = attr.Value
+				}
+			}
+			groups.occurs[key] = o
+		}
+	}
+	return groups, xml.NewTokenDecoder(&tokens).Decode(v)
+}
+
+// tokenReplay reads tokens read before.
+type tokenReplay []xml.Token
+
+func (r *tokenReplay) Token() (xml.Token, error) {
+	if len(*r) == 0 {
+		return nil, io.EOF
+	}
+	tok := (*r)[0]
+	*r = (*r)[1:]
+	return tok, nil
 }
 
 // XSDGroup element is used to define a group of elements to be used in complex type definitions.
 type XSDGroup struct {
-	Name     string       `xml:"name,attr"`
-	Ref      string       `xml:"ref,attr"`
-	Sequence []XSDElement `xml:"sequence>element"`
-	Choice   []XSDElement `xml:"choice>element"`
-	All      []XSDElement `xml:"all>element"`
+	Name         string       `xml:"name,attr"`
+	Ref          string       `xml:"ref,attr"`
+	MinOccurs    string       `xml:"minOccurs,attr"`
+	MaxOccurs    string       `xml:"maxOccurs,attr"`
+	Sequence     []XSDElement `xml:"sequence>element"`
+	Choice       []XSDElement `xml:"choice>element"`
+	All          []XSDElement `xml:"all>element"`
+	Groups       []*XSDGroup  `xml:"sequence>group"`
+	ChoiceGroups []*XSDGroup  `xml:"choice>group"`
+
+	// Number of elements preceding each group reference in the sequence
+	// or the choice, for groups to be expanded in place.
+	GroupPositions       []int `xml:"-"`
+	ChoiceGroupPositions []int `xml:"-"`
+}
+
+// UnmarshalXML implements interface xml.Unmarshaler for XSDGroup.
+func (g *XSDGroup) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type group XSDGroup
+	groups, err := decodeModelGroups(d, start, (*group)(g))
+	if err != nil {
+		return err
+	}
+	g.GroupPositions = groups.positions["sequence"]
+	g.ChoiceGroupPositions = groups.positions["choice"]
+	return nil
 }
 
 // XSDComplexContent element defines extensions or restrictions on a complex
 // type that contains mixed content or elements only.
 type XSDComplexContent struct {
-	XMLName   xml.Name     `xml:"complexContent"`
-	Extension XSDExtension `xml:"extension"`
+	XMLName     xml.Name     `xml:"complexContent"`
+	Extension   XSDExtension `xml:"extension"`
+	Restriction XSDExtension `xml:"restriction"`
 }
 
 // XSDSimpleContent element contains extensions or restrictions on a text-only
@@ -187,13 +358,42 @@ type XSDSimpleContent struct {
 }
 
 // XSDExtension element extends an existing simpleType or complexType element.
+// It also holds the restrictions of complex content, which declare their
+// content the same way; XMLName tells them apart.
 type XSDExtension struct {
-	XMLName        xml.Name        `xml:"extension"`
+	XMLName        xml.Name
 	Base           string          `xml:"base,attr"`
 	Attributes     []*XSDAttribute `xml:"attribute"`
 	Sequence       []*XSDElement   `xml:"sequence>element"`
 	Choice         []*XSDElement   `xml:"choice>element"`
 	SequenceChoice []*XSDElement   `xml:"sequence>choice>element"`
+	Groups         []*XSDGroup     `xml:"sequence>group"`
+	ChoiceGroups   []*XSDGroup     `xml:"choice>group"`
+
+	// Occurrence constraints of the model groups the elements belong to.
+	SequenceOccurs       XSDOccurs `xml:"-"`
+	ChoiceOccurs         XSDOccurs `xml:"-"`
+	SequenceChoiceOccurs XSDOccurs `xml:"-"`
+
+	// Number of elements preceding each group reference in the sequence
+	// or the choice, for groups to be expanded in place.
+	GroupPositions       []int `xml:"-"`
+	ChoiceGroupPositions []int `xml:"-"`
+}
+
+// UnmarshalXML implements interface xml.Unmarshaler for XSDExtension.
+func (ext *XSDExtension) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type extension XSDExtension
+	groups, err := decodeModelGroups(d, start, (*extension)(ext))
+	if err != nil {
+		return err
+	}
+	ext.SequenceOccurs = groups.occurs["sequence"]
+	ext.ChoiceOccurs = groups.occurs["choice"]
+	ext.SequenceChoiceOccurs = groups.occurs["sequence>choice"]
+	ext.GroupPositions = groups.positions["sequence"]
+	ext.ChoiceGroupPositions = groups.positions["choice"]
+	return nil
 }
 
 // XSDAttribute represent an element attribute. Simple elements cannot have